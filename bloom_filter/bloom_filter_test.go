@@ -0,0 +1,72 @@
+package bloom_filter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func filled(n int, falsePositiveRate float64) *BloomFilterUnique {
+	filter := NewBloomFilterUnique(n, falsePositiveRate)
+	for i := 0; i < n; i++ {
+		filter.AddString(fmt.Sprint("key", i))
+	}
+	return filter
+}
+
+// Dodati kljucevi se uvek nalaze, i u filteru i direktno u serijalizovanom obliku
+func TestNoFalseNegatives(t *testing.T) {
+	filter := filled(5000, 0.01)
+	data := filter.Save()
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprint("key", i)
+		if !filter.Contains(key) || !MayContain(data, key) {
+			t.Fatalf("%s not found", key)
+		}
+	}
+}
+
+func TestFalsePositiveRate(t *testing.T) {
+	filter := filled(10000, 0.01)
+	data := filter.Save()
+	positives := 0
+	for i := 0; i < 100000; i++ {
+		key := fmt.Sprint("other", i)
+		if MayContain(data, key) {
+			positives++
+		}
+		if MayContain(data, key) != filter.Contains(key) {
+			t.Fatalf("MayContain and Contains disagree on %s", key)
+		}
+	}
+	if rate := float64(positives) / 100000; rate > 0.015 {
+		t.Errorf("false positive rate %.4f, want about 0.01", rate)
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	filter := filled(100, 0.01)
+	loaded, err := Decode(filter.Save())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Hashes() != filter.Hashes() || loaded.Bits() != filter.Bits() {
+		t.Fatalf("loaded k=%d bits=%d, want k=%d bits=%d", loaded.Hashes(), loaded.Bits(), filter.Hashes(), filter.Bits())
+	}
+	for i := 0; i < 100; i++ {
+		if !loaded.Contains(fmt.Sprint("key", i)) {
+			t.Fatalf("key%d lost after Save/Decode", i)
+		}
+	}
+}
+
+// Neispravni podaci ne smeju odbiti kljuc, a Decode vraca gresku
+func TestInvalidData(t *testing.T) {
+	data := filled(100, 0.01).Save()
+	truncated := data[:len(data)-1]
+	if _, err := Decode(truncated); err == nil {
+		t.Error("truncated filter was decoded")
+	}
+	if !MayContain(truncated, "missing") || !MayContain(nil, "missing") {
+		t.Error("invalid filter rejected a key")
+	}
+}
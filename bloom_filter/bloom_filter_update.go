@@ -1,115 +1,211 @@
 package bloom_filter
 
 import (
-	"bytes"
-	"encoding/gob"
-	"math"
-	"crypto/md5"
 	"encoding/binary"
-	"time"
+	"errors"
+	"math"
 )
-type HashWithSeed struct {
-	Seed []byte
-}
 
-func (h HashWithSeed) Hash(data []byte) uint64 {
-	fn := md5.New()
-	fn.Write(append(data, h.Seed...))
-	return binary.BigEndian.Uint64(fn.Sum(nil))
-}
+const (
+	BLOCK_BITS    = 512 // jedan blok je jedna kes linija od 64 bajta
+	BLOCK_WORDS   = BLOCK_BITS / 64
+	BLOOM_VERSION = 2
+	BLOOM_HEADER  = 6 // verzija (1B) | broj hes funkcija (1B) | broj blokova (4B)
+	MAX_HASHES    = 16
+)
 
-func CreateHashFunctions(k uint) []HashWithSeed {
-	h := make([]HashWithSeed, k)
-	ts := uint(time.Now().Unix())
-	for i := uint(0); i < k; i++ {
-		seed := make([]byte, 32)
-		binary.BigEndian.PutUint32(seed, uint32(ts+i))
-		hfn := HashWithSeed{Seed: seed}
-		h[i] = hfn
-	}
-	return h
-}
+/*
+Bloom filter podeljen na blokove velicine kes linije. Kljuc se jednim 64-bitnim hesom
+preslikava u blok, a svih k bitova kljuca je u tom bloku, pa provera cita samo jednu kes
+liniju. Bitovi u bloku se biraju dvostrukim hesiranjem (g1 + i*g2) iz istog hesa, bez
+seed-ova, pa isti kljuc uvek daje iste bitove i filter se moze proveravati direktno iz
+serijalizovanog oblika (vidi MayContain).
+*/
 type BloomFilterUnique struct {
-	M             uint
-	Data          []byte
-	HashFunctions []HashWithSeed
+	k      uint32
+	blocks []uint64 // BLOCK_WORDS reci po bloku
 }
-func CalculateM(expectedElements int, falsePositiveRate float64) uint {
-	return uint(math.Ceil(float64(expectedElements) * math.Abs(math.Log(falsePositiveRate)) / math.Pow(math.Log(2), float64(2))))
+
+// Broj bitova po kljucu za datu verovatnocu lazno pozitivnog odgovora. Blokovi se pune
+// neravnomerno, pa se dodaje oko osmine bitova da bi stvarna verovatnoca ostala ispod zadate.
+func BitsPerKey(falsePositiveRate float64) int {
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.01
+	}
+	bits := math.Ceil(-math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	return int(bits + math.Ceil(bits/8))
 }
 
-func CalculateK(expectedElements int, m uint) uint {
-	return uint(math.Ceil((float64(m) / float64(expectedElements)) * math.Log(2)))
+// Optimalan broj hes funkcija za bitove bez dodatka iz BitsPerKey, jer veci broj bitova po
+// kljucu dodatno puni najpunije blokove
+func numHashes(bitsPerKey int) uint32 {
+	k := uint32(math.Round(float64(bitsPerKey) * math.Ln2 * 8 / 9))
+	if k < 1 {
+		k = 1
+	}
+	if k > MAX_HASHES {
+		k = MAX_HASHES
+	}
+	return k
 }
+
 // Konstruktor za bloomfilter
 // expectedElements -> ocekivani broj elemenata
 // falsePositiveRate -> tolerancija na gresku
 func NewBloomFilterUnique(expectedElements int, falsePositiveRate float64) *BloomFilterUnique {
-	m := CalculateM(expectedElements, falsePositiveRate) // broj bitova
-	k := CalculateK(expectedElements, m)                 // broj hash funkcija
+	return NewBloomFilterBits(expectedElements, BitsPerKey(falsePositiveRate))
+}
 
-	hashFunctions := CreateHashFunctions(k) // hash funkcije
-	bytesNum := math.Ceil(float64(m) / 8)   // broj bajtova
-	data := make([]byte, int(bytesNum))     // niz velicine m
+// Filter sa bitsPerKey bitova po ocekivanom elementu
+func NewBloomFilterBits(expectedElements int, bitsPerKey int) *BloomFilterUnique {
+	if expectedElements < 1 {
+		expectedElements = 1
+	}
+	if bitsPerKey < 2 {
+		bitsPerKey = 2
+	}
+	numBlocks := (expectedElements*bitsPerKey + BLOCK_BITS - 1) / BLOCK_BITS
+	return &BloomFilterUnique{
+		k:      numHashes(bitsPerKey),
+		blocks: make([]uint64, numBlocks*BLOCK_WORDS),
+	}
+}
 
-	b := BloomFilterUnique{m, data, hashFunctions}
+// FNV-1a sa finalizerom iz MurmurHash3, bez alokacija i bez seed-a
+func hashKey(key string) uint64 {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= 1099511628211
+	}
+	hash ^= hash >> 33
+	hash *= 0xff51afd7ed558ccd
+	hash ^= hash >> 33
+	hash *= 0xc4ceb9fe1a85ec53
+	hash ^= hash >> 33
+	return hash
+}
 
-	return &b
+// Gornjih 32 bita hesa bira blok (mnozenjem umesto deljenjem), a donjih 32 bita i njihova
+// kombinacija sa gornjim daju dve hes funkcije za bitove unutar bloka
+func probe(hash uint64, numBlocks uint32) (block uint32, g1 uint32, g2 uint32) {
+	block = uint32((hash >> 32) * uint64(numBlocks) >> 32)
+	g1 = uint32(hash)
+	g2 = uint32(hash>>32)*0x9e3779b9 | 1
+	return block, g1, g2
 }
 
 // Dodavanje elementa u bloomfilter
 // data -> element za dodavanje
-func (b BloomFilterUnique) Add(data []byte) {
-	for _, hashFunction := range b.HashFunctions {
-		hashed := hashFunction.Hash(data)
-		bit := hashed % uint64(b.M) // bit u nizu
+func (b *BloomFilterUnique) Add(data []byte) {
+	b.AddString(string(data))
+}
 
-		targetByte := bit / 8     // bajt u kome se bit nalazi
-		bitMask := 1 << (bit % 8) // maska
-		index := int(targetByte)
-		b.Data[index] = b.Data[index] | byte(bitMask) // bitwise OR kako bi upisali jedinicu
+func (b *BloomFilterUnique) AddString(key string) {
+	block, g1, g2 := probe(hashKey(key), uint32(len(b.blocks)/BLOCK_WORDS))
+	words := b.blocks[block*BLOCK_WORDS : (block+1)*BLOCK_WORDS]
+	for i := uint32(0); i < b.k; i++ {
+		bit := g1 >> 23 // gornjih 9 bitova, pozicija u bloku od 512 bitova
+		words[bit/64] |= 1 << (bit % 64)
+		g1 += g2
 	}
 }
 
 // Citanje elementa
 // data -> element za citanje
-func (b BloomFilterUnique) Read(data []byte) bool {
-	for _, hashFunction := range b.HashFunctions {
-		// Isto kao kod pisanja
-		hashed := hashFunction.Hash(data)
-		bit := hashed % uint64(b.M)
-
-		targetByte := bit / 8
-		bitMask := 1 << (bit % 8)
-		index := int(targetByte)
-
-		// bitwise AND kako bi proverili da li je bit na datoj poziciji
-		if b.Data[index]&byte(bitMask) == 0 {
+func (b *BloomFilterUnique) Read(data []byte) bool {
+	return b.Contains(string(data))
+}
+
+func (b *BloomFilterUnique) Contains(key string) bool {
+	block, g1, g2 := probe(hashKey(key), uint32(len(b.blocks)/BLOCK_WORDS))
+	words := b.blocks[block*BLOCK_WORDS : (block+1)*BLOCK_WORDS]
+	for i := uint32(0); i < b.k; i++ {
+		bit := g1 >> 23
+		if words[bit/64]&(1<<(bit%64)) == 0 {
 			return false
 		}
+		g1 += g2
 	}
-
 	return true
 }
 
-func (b BloomFilterUnique) Save() []byte {
-	var buffer bytes.Buffer
-	encoder := gob.NewEncoder(&buffer)
-	encoder.Encode(b)
+// Velicina filtera u bitovima
+func (b *BloomFilterUnique) Bits() int {
+	return len(b.blocks) * 64
+}
 
-	return buffer.Bytes()
+func (b *BloomFilterUnique) Hashes() int {
+	return int(b.k)
 }
 
-func Load(data []byte) *BloomFilterUnique {
-	var buffer bytes.Buffer
-	buffer.Write(data)
-	decoder := gob.NewDecoder(&buffer)
+// verzija | k | broj blokova (4B) | reci blokova (8B, little-endian)
+func (b *BloomFilterUnique) Save() []byte {
+	data := make([]byte, BLOOM_HEADER, BLOOM_HEADER+len(b.blocks)*8)
+	data[0] = BLOOM_VERSION
+	data[1] = byte(b.k)
+	binary.LittleEndian.PutUint32(data[2:], uint32(len(b.blocks)/BLOCK_WORDS))
+	for _, word := range b.blocks {
+		data = binary.LittleEndian.AppendUint64(data, word)
+	}
+	return data
+}
 
-	b := &BloomFilterUnique{}
-	err := decoder.Decode(b)
+func Load(data []byte) *BloomFilterUnique {
+	b, err := Decode(data)
 	if err != nil {
 		panic("error while decoding")
 	}
 
 	return b
-}
\ No newline at end of file
+}
+
+var errInvalidFilter = errors.New("bloom_filter: invalid encoded filter")
+
+// Broj hes funkcija i blokova iz zaglavlja serijalizovanog filtera
+func header(data []byte) (uint32, uint32, error) {
+	if len(data) < BLOOM_HEADER || data[0] != BLOOM_VERSION {
+		return 0, 0, errInvalidFilter
+	}
+	k := uint32(data[1])
+	numBlocks := binary.LittleEndian.Uint32(data[2:])
+	if k == 0 || k > MAX_HASHES || numBlocks == 0 || uint64(len(data)-BLOOM_HEADER) != uint64(numBlocks)*BLOCK_BITS/8 {
+		return 0, 0, errInvalidFilter
+	}
+	return k, numBlocks, nil
+}
+
+// Isto kao Load, ali vraca gresku umesto panike kada podaci nisu ispravni
+func Decode(data []byte) (*BloomFilterUnique, error) {
+	k, numBlocks, err := header(data)
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]uint64, numBlocks*BLOCK_WORDS)
+	for i := range blocks {
+		blocks[i] = binary.LittleEndian.Uint64(data[BLOOM_HEADER+i*8:])
+	}
+	return &BloomFilterUnique{k: k, blocks: blocks}, nil
+}
+
+/*
+Provera kljuca direktno nad serijalizovanim filterom (izlazom Save), bez dekodiranja i bez
+alokacija, pa citaoci SSTabela ne moraju da prave filter za svaku proveru. Za neispravne
+podatke vraca true, jer filter sme da pogresi samo u korist citanja tabele.
+*/
+func MayContain(data []byte, key string) bool {
+	k, numBlocks, err := header(data)
+	if err != nil {
+		return true
+	}
+	block, g1, g2 := probe(hashKey(key), numBlocks)
+	words := data[BLOOM_HEADER+block*BLOCK_BITS/8:]
+	for i := uint32(0); i < k; i++ {
+		bit := g1 >> 23
+		if words[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+		g1 += g2
+	}
+	return true
+}
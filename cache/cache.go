@@ -16,76 +16,76 @@ LRU - algorithm used for this cache that moves the newest elements to the front
 type Cache struct {
 	MaxLength int
 	Length    int
-	MapItems  map[string]interface{}
-	ListLRU   *list.List // double-linked list from imported library
+	MapItems  map[string]*list.Element // key -> list element holding that key's entry
+	ListLRU   *list.List               // double-linked list from imported library
+	Pinned    map[string]bool          // keys that are not evicted while the cache has other elements
+}
+
+// list element value, the key is kept so that an element can be found in the map and checked against Pinned
+type entry struct {
+	key   string
+	value interface{}
 }
 
 func NewCache(maxLength int) *Cache {
 	return &Cache{
 		MaxLength: maxLength,
 		Length:    0,
-		MapItems:  make(map[string]interface{}),
+		MapItems:  make(map[string]*list.Element),
 		ListLRU:   list.New(),
+		Pinned:    make(map[string]bool),
+	}
+}
+
+// replaces the set of pinned keys (e.g. the most frequently used keys)
+func (cache *Cache) SetPinned(keys []string) {
+	cache.Pinned = make(map[string]bool)
+	for _, key := range keys {
+		cache.Pinned[key] = true
 	}
 }
 
 func (cache *Cache) AddItem(key string, value interface{}) {
 
 	// 1. case: the object that should be added already exists in cache
-	currentValue, exist := cache.MapItems[key]
-	if exist {
-		currentElement := findByValue(cache.ListLRU, currentValue)
-		cache.ListLRU.MoveToFront(currentElement)
-		// cache.ListLRU.MoveToFront(value.(*list.Element))
-		// unsuccessfully - string or some other type and *list.Element are different types
-
-		// updating existing object (same key, different value)
-		if currentValue != value {
-			first := cache.ListLRU.Front()
-			cache.ListLRU.Remove(first)
-			cache.ListLRU.PushFront(value)
-			cache.MapItems[key] = value
-		}
+	// (same key, possibly different value) - update it and move it to the front
+	if elem, exist := cache.MapItems[key]; exist {
+		elem.Value.(*entry).value = value
+		cache.ListLRU.MoveToFront(elem)
 		return
 	}
 
 	// 2. case: add new object (simply push front)
 	if cache.Length == cache.MaxLength { // list is full
-		lastElem := cache.ListLRU.Back()
+		lastElem := cache.evictionCandidate()
 		cache.ListLRU.Remove(lastElem)
+		delete(cache.MapItems, lastElem.Value.(*entry).key)
 		cache.Length--
-
-		for k, v := range cache.MapItems {
-			if v == lastElem.Value {
-				delete(cache.MapItems, k)
-			}
-		}
 	}
 
-	cache.ListLRU.PushFront(value)
-	cache.MapItems[key] = value
+	cache.MapItems[key] = cache.ListLRU.PushFront(&entry{key, value})
 	cache.Length++
 }
 
-// helper function that finds an element in list by value and returns it
-func findByValue(listLRU *list.List, value interface{}) *list.Element {
-	for elem := listLRU.Front(); elem != nil; elem = elem.Next() {
-		if elem.Value == value {
+// the least recently used element that does not belong to a pinned key,
+// or simply the least recently used one if all elements are pinned
+func (cache *Cache) evictionCandidate() *list.Element {
+	for elem := cache.ListLRU.Back(); elem != nil; elem = elem.Prev() {
+		if !cache.Pinned[elem.Value.(*entry).key] {
 			return elem
 		}
 	}
-	return nil
+	return cache.ListLRU.Back()
 }
 
-// iterate through the map and try to find by key
+// find the element by key in the map
 // return: (value, true) if exists, else (nil, false)
 func (cache *Cache) GetByKey(key string) (bool, interface{}) {
-	value, exist := cache.MapItems[key]
+	elem, exist := cache.MapItems[key]
 	if exist {
 		// each read element should be put on the start as the newest
-		movingElem := findByValue(cache.ListLRU, value)
-		cache.ListLRU.MoveToFront(movingElem)
-		return true, cache.MapItems[key]
+		cache.ListLRU.MoveToFront(elem)
+		return true, elem.Value.(*entry).value
 	}
 	return false, nil
 }
@@ -98,13 +98,14 @@ func (cache *Cache) DeleteByKey(key string) {
 	}
 
 	delete(cache.MapItems, key)
-	cache.ListLRU.Remove(findByValue(cache.ListLRU, elem))
+	cache.ListLRU.Remove(elem)
+	cache.Length--
 }
 
 // print elements from the cache list from the newest to the oldest
 func (cache *Cache) Print() {
 	for elem := cache.ListLRU.Front(); elem != nil; elem = elem.Next() {
-		fmt.Print(elem.Value, " ")
+		fmt.Print(elem.Value.(*entry).value, " ")
 	}
 	fmt.Println()
 }
@@ -131,9 +132,9 @@ func TestCache() {
 		key := util.RandomString(1, i)
 		fmt.Println("Element to add:", key)
 
-		exist := false
-		if exist, _ = cache.GetByKey(key); exist {
-			fmt.Println("Element", cache.MapItems[key], "already exists.")
+		exist, value := cache.GetByKey(key)
+		if exist {
+			fmt.Println("Element", value, "already exists.")
 		}
 		if !exist {
 			cache.AddItem(key, key)
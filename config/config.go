@@ -43,11 +43,37 @@ const (
 	KEY_START             = VALUE_SIZE_START + VALUE_SIZE_SIZE
 	HYPERLOGLOG_PRECISION = 8
 	HYPERLOGLOG64BITHASH  = false
+	HLL_PLUS_PRECISION    = 14   // preciznost HyperLogLog++ skica koje pravi engine
+	KEY_SKETCH_CHUNK      = 1024 // broj kljuceva SSTabele po jednoj skici razlicitih kljuceva
+	SIMHASH_BANDS         = 4    // broj traka 64-bitnog otiska u indeksu skoro istih vrednosti
+	SIMHASH_TOKENIZER     = "words"
+	SIMHASH_NGRAM         = 3  // duzina n-grama karaktera, odnosno broj reci u shingle-u
+	PREFIX_EXTRACTOR      = "" // "fixed" ili "delimiter", prazno znaci bez prefiksnog filtera
+	PREFIX_LENGTH         = 4
+	PREFIX_DELIMITER      = ":"
+	FILTER_TYPE           = "bloom" // filter particija SSTabele: "bloom" ili "xor"
+	MEMTABLE_FILTER       = false   // cuckoo filter kljuceva u memtabelama
+	DDSKETCH_ACCURACY     = 0.01    // relativna greska kvantila velicina vrednosti i trajanja operacija
 	WAL_DATA_SIZE         = 2
 	WAL_FILE_SIZE         = 20
 	WAL_LOW_WATER_MARK    = 2
 	SSTABLE_DEGREE        = 0
 	SSTABLE_ALL_IN_ONE    = true
+	INDEX_PARTITION_SIZE  = 16
+	UNIVERSAL_SIZE_RATIO  = 1   // procenat za koji sledeci run sme biti veci od zbira prethodnih
+	UNIVERSAL_MIN_MERGE   = 2   // najmanji broj run-ova koji se spajaju po size ratio pravilu
+	UNIVERSAL_MAX_AMP     = 200 // procenat: zbir svih run-ova osim najstarijeg / najstariji run
+	UNIVERSAL_TRIGGER     = 4   // broj run-ova od kog pocinje universal kompakcija
+	FIFO_MAX_TOTAL_SIZE   = 1048576
+	MERGE_OPERATOR        = "int64Add"
+	COMPACTION_RATE_LIMIT = 4194304 // bajtova u sekundi za upis tabela pri flush-u i kompakciji
+	READ_WEIGHT           = 1       // tokena po GET-u
+	WRITE_WEIGHT          = 2       // tokena po PUT/DELETE/MERGE-u
+	SCAN_WEIGHT           = 1       // tokena po kljucu stranice skeniranja
+	BYTES_PER_TOKEN       = 1024    // procitani ili upisani bajtovi koji se placaju jednim tokenom
+	HOT_KEYS              = 10      // broj najcesce koriscenih kljuceva koje engine prati
+	CMS_CONSERVATIVE      = true
+	CMS_DECAY_INTERVAL    = 3600 // sekundi izmedju dva polovljenja brojaca, 0 bez opadanja
 )
 
 type Config struct {
@@ -83,11 +109,42 @@ type Config struct {
 	BTreeOrder             int     `json:"bTreeOrder"`
 	HyperloglogPrecision   int     `json:"HyperloglogPrecision"`
 	Hyperloglog64bitHash   bool    `json:"Hyperloglog64bitHash"`
+	HllPlusPrecision       int     `json:"hllPlusPrecision"`
+	KeySketchChunk         int     `json:"keySketchChunk"`
+	PrefixExtractor        string  `json:"prefixExtractor"`
+	PrefixLength           int     `json:"prefixLength"`
+	PrefixDelimiter        string  `json:"prefixDelimiter"`
+	FilterType             string  `json:"filterType"`
+	MemtableFilter         bool    `json:"memtableFilter"`
+	DDSketchAccuracy       float64 `json:"ddsketchAccuracy"`
 	WalFileSize            int     `json:"WalFileSize"`
 	WalDataSize            int     `json:"WalDataSize"`
 	WalLowWaterMark        int     `json:"WalLowWaterMark"`
 	SStableDegree          int     `json:"SStableDegree"`
 	SStableAllInOne        bool    `json:"SStableAllInOne"`
+	IndexPartitionSize     int     `json:"IndexPartitionSize"`
+	UniversalSizeRatio     int     `json:"universalSizeRatio"`
+	UniversalMinMerge      int     `json:"universalMinMerge"`
+	UniversalMaxAmp        int     `json:"universalMaxAmp"`
+	UniversalTrigger       int     `json:"universalTrigger"`
+	FifoMaxTotalSize       int     `json:"fifoMaxTotalSize"`
+	MergeOperator          string  `json:"mergeOperator"`
+	CompactionRateLimit    int     `json:"compactionRateLimit"`
+	ReadTokenNumber        int     `json:"readTokenNumber"`
+	WriteTokenNumber       int     `json:"writeTokenNumber"`
+	ReadWeight             int     `json:"readWeight"`
+	WriteWeight            int     `json:"writeWeight"`
+	ScanWeight             int     `json:"scanWeight"`
+	BytesPerToken          int     `json:"bytesPerToken"`
+	HotKeys                int     `json:"hotKeys"`
+	CmsConservative        bool    `json:"cmsConservative"`
+	CmsDecayInterval       float64 `json:"cmsDecayInterval"`
+	// indeks skoro istih vrednosti, vidi engine.FindNearDuplicates
+	SimHashPrefixes  []string `json:"simhashPrefixes"`
+	SimHashBands     int      `json:"simhashBands"`
+	SimHashTokenizer string   `json:"simhashTokenizer"`
+	SimHashNGram     int      `json:"simhashNGram"`
+	SimHashStopwords []string `json:"simhashStopwords"`
 }
 
 func NewConfig(filename string) *Config {
@@ -126,11 +183,39 @@ func NewConfig(filename string) *Config {
 		config.KeyStart = KEY_START
 		config.HyperloglogPrecision = HYPERLOGLOG_PRECISION
 		config.Hyperloglog64bitHash = HYPERLOGLOG64BITHASH
+		config.HllPlusPrecision = HLL_PLUS_PRECISION
+		config.KeySketchChunk = KEY_SKETCH_CHUNK
+		config.SimHashBands = SIMHASH_BANDS
+		config.SimHashTokenizer = SIMHASH_TOKENIZER
+		config.SimHashNGram = SIMHASH_NGRAM
+		config.PrefixExtractor = PREFIX_EXTRACTOR
+		config.PrefixLength = PREFIX_LENGTH
+		config.PrefixDelimiter = PREFIX_DELIMITER
+		config.FilterType = FILTER_TYPE
+		config.MemtableFilter = MEMTABLE_FILTER
+		config.DDSketchAccuracy = DDSKETCH_ACCURACY
 		config.WalDataSize = WAL_DATA_SIZE
 		config.WalFileSize = WAL_FILE_SIZE
 		config.WalLowWaterMark = WAL_LOW_WATER_MARK
 		config.SStableDegree = SSTABLE_DEGREE
 		config.SStableAllInOne = SSTABLE_ALL_IN_ONE
+		config.IndexPartitionSize = INDEX_PARTITION_SIZE
+		config.UniversalSizeRatio = UNIVERSAL_SIZE_RATIO
+		config.UniversalMinMerge = UNIVERSAL_MIN_MERGE
+		config.UniversalMaxAmp = UNIVERSAL_MAX_AMP
+		config.UniversalTrigger = UNIVERSAL_TRIGGER
+		config.FifoMaxTotalSize = FIFO_MAX_TOTAL_SIZE
+		config.MergeOperator = MERGE_OPERATOR
+		config.CompactionRateLimit = COMPACTION_RATE_LIMIT
+		config.ReadTokenNumber = TOKEN_NUMBER
+		config.WriteTokenNumber = TOKEN_NUMBER
+		config.ReadWeight = READ_WEIGHT
+		config.WriteWeight = WRITE_WEIGHT
+		config.ScanWeight = SCAN_WEIGHT
+		config.BytesPerToken = BYTES_PER_TOKEN
+		config.HotKeys = HOT_KEYS
+		config.CmsConservative = CMS_CONSERVATIVE
+		config.CmsDecayInterval = CMS_DECAY_INTERVAL
 	} else {
 		err = json.Unmarshal(yamlFile, &config)
 		if err != nil {
@@ -1,11 +1,12 @@
 package countMinSketch
 
 import (
-	"encoding/gob"
+	"bytes"
+	"errors"
 	"fmt"
 	"math"
-	"os"
 	"projekat_nasp/util"
+	"time"
 )
 
 type CountMinSketch struct {
@@ -13,6 +14,9 @@ type CountMinSketch struct {
 	M             uint           // number of columns
 	Counters      [][]uint       // matrix with cells
 	HashFunctions []HashWithSeed //k hash functions
+	Conservative  bool           // conservative update: only the smallest counters of a key are incremented
+	DecayInterval time.Duration  // all counters are halved once per interval, 0 means no decay
+	LastDecay     int64          // unix nanoseconds of the last decay
 }
 
 func NewCountMinSketch(precision float64, safety float64) *CountMinSketch {
@@ -53,20 +57,40 @@ func (cms *CountMinSketch) Print() {
 }
 
 // (i, j) - coordinates in cms matrix
-func (cms *CountMinSketch) AddKey(key string) {
+func (cms *CountMinSketch) columns(key string) []uint64 {
+	columns := make([]uint64, len(cms.HashFunctions))
 	for i, hashFunction := range cms.HashFunctions {
 		hashValue := hashFunction.Hash([]byte(key))
-		j := hashValue % uint64(cms.M)
-		cms.Counters[i][j]++
+		columns[i] = hashValue % uint64(cms.M)
+	}
+	return columns
+}
+
+// with conservative update only counters equal to the current estimate are incremented,
+// the others already overestimate the key, which lowers the error for rare keys
+func (cms *CountMinSketch) AddKey(key string) {
+	columns := cms.columns(key)
+	if !cms.Conservative {
+		for i, j := range columns {
+			cms.Counters[i][j]++
+		}
+		return
+	}
+	estimate := cms.estimate(columns)
+	for i, j := range columns {
+		if cms.Counters[i][j] == estimate {
+			cms.Counters[i][j]++
+		}
 	}
 }
 
 func (cms *CountMinSketch) FindKeyFrequency(key string) uint {
-	minFreqValue := uint(math.MaxUint64)
-	for i, hashFunction := range cms.HashFunctions {
-		hashValue := hashFunction.Hash([]byte(key))
-		j := hashValue % uint64(cms.M)
+	return cms.estimate(cms.columns(key))
+}
 
+func (cms *CountMinSketch) estimate(columns []uint64) uint {
+	minFreqValue := uint(math.MaxUint64)
+	for i, j := range columns {
 		if minFreqValue > cms.Counters[i][j] {
 			minFreqValue = cms.Counters[i][j]
 		}
@@ -74,29 +98,54 @@ func (cms *CountMinSketch) FindKeyFrequency(key string) uint {
 	return minFreqValue
 }
 
-func WriteGob(filePath string, object interface{}) error {
-	file, err := os.Create(filePath)
-	if err == nil {
-		encoder := gob.NewEncoder(file)
-		encoder.Encode(object)
+// halves all counters once for every full DecayInterval since the last decay, so old
+// popularity fades. Returns how many times the counters were halved.
+func (cms *CountMinSketch) DecayIfDue(now time.Time) uint {
+	if cms.DecayInterval <= 0 {
+		return 0
+	}
+	if cms.LastDecay == 0 {
+		cms.LastDecay = now.UnixNano()
+		return 0
 	}
-	file.Close()
-	return err
+	intervals := (now.UnixNano() - cms.LastDecay) / int64(cms.DecayInterval)
+	if intervals <= 0 {
+		return 0
+	}
+	cms.LastDecay += intervals * int64(cms.DecayInterval)
+	shift := uint(intervals)
+	if shift > 63 {
+		shift = 63
+	}
+	for i := range cms.Counters {
+		for j := range cms.Counters[i] {
+			cms.Counters[i][j] >>= shift
+		}
+	}
+	return shift
 }
 
-func ReadGob(filePath string, object interface{}) error {
-	file, err := os.Open(filePath)
-	if err == nil {
-		decoder := gob.NewDecoder(file)
-		err = decoder.Decode(object)
+// adds the counters of another sketch, both must have the same dimensions and hash functions
+func (cms *CountMinSketch) Merge(other *CountMinSketch) error {
+	if cms.K != other.K || cms.M != other.M || len(cms.HashFunctions) != len(other.HashFunctions) {
+		return errors.New("countMinSketch: sketches have different dimensions")
+	}
+	for i := range cms.HashFunctions {
+		if !bytes.Equal(cms.HashFunctions[i].Seed, other.HashFunctions[i].Seed) {
+			return errors.New("countMinSketch: sketches have different hash functions")
+		}
+	}
+	for i := range cms.Counters {
+		for j := range cms.Counters[i] {
+			cms.Counters[i][j] += other.Counters[i][j]
+		}
 	}
-	file.Close()
-	return err
+	return nil
 }
 
 /*
 -test function for cms that 1000 times adds random different keys and 1000 times the same key
--serialize to test.gob file
+-serialize to test.cms file
 -print the frequency of test object, which should be near 1000
 -deserialize the file and print its content to check the correctness of the serialization
 */
@@ -112,13 +161,12 @@ func RunExample() {
 	cms.Print()
 	fmt.Println(cms.FindKeyFrequency(test))
 
-	err := WriteGob("./test.gob", cms)
+	err := cms.WriteToFile("./test.cms")
 	if err != nil {
 		fmt.Println(err)
 	}
 
-	var newCms = new(CountMinSketch)
-	err = ReadGob("./test.gob", newCms)
+	newCms, err := ReadFromFile("./test.cms")
 	if err != nil {
 		fmt.Println(err)
 	} else {
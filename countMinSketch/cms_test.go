@@ -0,0 +1,68 @@
+package countMinSketch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSerializeRoundTrip(t *testing.T) {
+	cms := NewCountMinSketch(0.01, 0.99)
+	cms.Conservative = true
+	cms.DecayInterval = time.Hour
+	cms.LastDecay = 12345
+	for i := 0; i < 300; i++ {
+		cms.AddKey("hot")
+	}
+	cms.AddKey("cold")
+
+	restored, err := Deserialize(cms.Serialize())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.K != cms.K || restored.M != cms.M || !restored.Conservative ||
+		restored.DecayInterval != time.Hour || restored.LastDecay != 12345 {
+		t.Fatalf("parameters not restored: %+v", restored)
+	}
+	for _, key := range []string{"hot", "cold", "missing"} {
+		if got, want := restored.FindKeyFrequency(key), cms.FindKeyFrequency(key); got != want {
+			t.Errorf("frequency of %q = %d, want %d", key, got, want)
+		}
+	}
+}
+
+func TestDeserializeRejectsTruncated(t *testing.T) {
+	data := NewCountMinSketch(0.1, 0.9).Serialize()
+	if _, err := Deserialize(data[:len(data)/2]); err == nil {
+		t.Fatal("truncated sketch was accepted")
+	}
+}
+
+// sketches with the same parameters built at different times must be mergeable,
+// also when one of them was restored from its serialized form
+func TestMergeSketchesBuiltSeparately(t *testing.T) {
+	first := NewCountMinSketch(0.01, 0.99)
+	for i := 0; i < 5; i++ {
+		first.AddKey("key")
+	}
+	restored, err := Deserialize(first.Serialize())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := NewCountMinSketch(0.01, 0.99)
+	for i := 0; i < 3; i++ {
+		second.AddKey("key")
+	}
+
+	if err := restored.Merge(second); err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.FindKeyFrequency("key"); got != 8 {
+		t.Errorf("merged frequency = %d, want 8", got)
+	}
+}
+
+func TestMergeRejectsDifferentDimensions(t *testing.T) {
+	if err := NewCountMinSketch(0.01, 0.99).Merge(NewCountMinSketch(0.1, 0.99)); err == nil {
+		t.Fatal("sketches with different dimensions were merged")
+	}
+}
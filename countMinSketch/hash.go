@@ -3,7 +3,6 @@ package countMinSketch
 import (
 	"crypto/md5"
 	"encoding/binary"
-	"time"
 )
 
 type HashWithSeed struct {
@@ -17,12 +16,13 @@ func (h HashWithSeed) Hash(data []byte) uint64 {
 	return binary.BigEndian.Uint64(fn.Sum(nil))
 }
 
+// seeds depend only on the row index, so sketches with the same parameters hash keys the
+// same way and can be merged, also with a sketch restored from an older run
 func CreateHashFunctions(k uint) []HashWithSeed {
 	h := make([]HashWithSeed, k)
-	ts := uint(time.Now().Unix())
 	for i := uint(0); i < k; i++ {
 		seed := make([]byte, 32)
-		binary.BigEndian.PutUint32(seed, uint32(ts+i))
+		binary.BigEndian.PutUint32(seed, uint32(i))
 		hfn := HashWithSeed{Seed: seed}
 		h[i] = hfn
 	}
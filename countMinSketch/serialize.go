@@ -0,0 +1,119 @@
+package countMinSketch
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"time"
+)
+
+const SERIALIZATION_VERSION = 1
+
+/*
+Compact binary format, all numbers are unsigned varints:
+
+	version | K | M | conservative (0/1) | decay interval (ns) | last decay (unix ns)
+	K x (seed length | seed)
+	K x M counters, row by row
+
+Most counters are small, so a varint usually takes one byte instead of eight.
+*/
+func (cms *CountMinSketch) Serialize() []byte {
+	bytes := make([]byte, 0, 64+int(cms.K)*(int(cms.M)+40))
+	bytes = binary.AppendUvarint(bytes, SERIALIZATION_VERSION)
+	bytes = binary.AppendUvarint(bytes, uint64(cms.K))
+	bytes = binary.AppendUvarint(bytes, uint64(cms.M))
+	var conservative uint64
+	if cms.Conservative {
+		conservative = 1
+	}
+	bytes = binary.AppendUvarint(bytes, conservative)
+	bytes = binary.AppendUvarint(bytes, uint64(cms.DecayInterval))
+	bytes = binary.AppendUvarint(bytes, uint64(cms.LastDecay))
+	for _, hashFunction := range cms.HashFunctions {
+		bytes = binary.AppendUvarint(bytes, uint64(len(hashFunction.Seed)))
+		bytes = append(bytes, hashFunction.Seed...)
+	}
+	for _, row := range cms.Counters {
+		for _, counter := range row {
+			bytes = binary.AppendUvarint(bytes, uint64(counter))
+		}
+	}
+	return bytes
+}
+
+func Deserialize(data []byte) (*CountMinSketch, error) {
+	reader := &varintReader{data: data}
+	if reader.next() != SERIALIZATION_VERSION {
+		return nil, errors.New("countMinSketch: unknown serialization version")
+	}
+	cms := &CountMinSketch{}
+	cms.K = uint(reader.next())
+	cms.M = uint(reader.next())
+	cms.Conservative = reader.next() == 1
+	cms.DecayInterval = time.Duration(reader.next())
+	cms.LastDecay = int64(reader.next())
+	if reader.err != nil || cms.K == 0 || cms.M == 0 || cms.K*cms.M > uint(len(data)) {
+		return nil, errors.New("countMinSketch: invalid serialized sketch")
+	}
+
+	cms.HashFunctions = make([]HashWithSeed, cms.K)
+	for i := range cms.HashFunctions {
+		cms.HashFunctions[i] = HashWithSeed{Seed: reader.bytes(reader.next())}
+	}
+	cms.Counters = make([][]uint, cms.K)
+	for i := range cms.Counters {
+		cms.Counters[i] = make([]uint, cms.M)
+		for j := range cms.Counters[i] {
+			cms.Counters[i][j] = uint(reader.next())
+		}
+	}
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	return cms, nil
+}
+
+func (cms *CountMinSketch) WriteToFile(filePath string) error {
+	return os.WriteFile(filePath, cms.Serialize(), 0644)
+}
+
+func ReadFromFile(filePath string) (*CountMinSketch, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return Deserialize(data)
+}
+
+// reads varints one after another and remembers the first error
+type varintReader struct {
+	data []byte
+	err  error
+}
+
+func (reader *varintReader) next() uint64 {
+	if reader.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(reader.data)
+	if n <= 0 {
+		reader.err = errors.New("countMinSketch: truncated serialized sketch")
+		return 0
+	}
+	reader.data = reader.data[n:]
+	return value
+}
+
+func (reader *varintReader) bytes(n uint64) []byte {
+	if reader.err != nil {
+		return nil
+	}
+	if n > uint64(len(reader.data)) {
+		reader.err = errors.New("countMinSketch: truncated serialized sketch")
+		return nil
+	}
+	bytes := append([]byte{}, reader.data[:n]...)
+	reader.data = reader.data[n:]
+	return bytes
+}
@@ -0,0 +1,109 @@
+package countMinSketch
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// key with its estimated frequency
+type HeavyHitter struct {
+	Key   string
+	Count uint
+}
+
+/*
+TopK keeps the k keys with the highest estimated frequency (heavy hitters).
+The estimates come from the sketch, so the structure only needs O(k) memory:
+a min-heap by count, whose root is replaced when a more frequent key shows up,
+and a map from key to its position in the heap.
+*/
+type TopK struct {
+	capacity int
+	items    hitterHeap
+	index    map[string]int
+}
+
+func NewTopK(capacity int) *TopK {
+	return &TopK{
+		capacity: capacity,
+		index:    make(map[string]int),
+	}
+}
+
+// updates the estimated frequency of the key, returns true if the set of heavy hitters changed
+func (topK *TopK) Update(key string, count uint) bool {
+	if i, ok := topK.index[key]; ok {
+		topK.items[i].Count = count
+		heap.Fix(topK, i)
+		return false
+	}
+	if topK.capacity <= 0 {
+		return false
+	}
+	if len(topK.items) < topK.capacity {
+		heap.Push(topK, HeavyHitter{key, count})
+		return true
+	}
+	if count <= topK.items[0].Count {
+		return false
+	}
+	delete(topK.index, topK.items[0].Key)
+	topK.items[0] = HeavyHitter{key, count}
+	topK.index[key] = 0
+	heap.Fix(topK, 0)
+	return true
+}
+
+// heavy hitters sorted by frequency, at most k of them
+func (topK *TopK) Items(k int) []HeavyHitter {
+	items := append([]HeavyHitter{}, topK.items...)
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Key < items[j].Key
+	})
+	if k >= 0 && k < len(items) {
+		items = items[:k]
+	}
+	return items
+}
+
+// divides all counts by 2^shift, used when the sketch the counts come from decays
+func (topK *TopK) Halve(shift uint) {
+	for i := range topK.items {
+		topK.items[i].Count >>= shift
+	}
+}
+
+func (topK *TopK) Contains(key string) bool {
+	_, ok := topK.index[key]
+	return ok
+}
+
+// heap.Interface, topK.items is a min-heap by count
+type hitterHeap []HeavyHitter
+
+func (topK *TopK) Len() int { return len(topK.items) }
+
+func (topK *TopK) Less(i, j int) bool { return topK.items[i].Count < topK.items[j].Count }
+
+func (topK *TopK) Swap(i, j int) {
+	topK.items[i], topK.items[j] = topK.items[j], topK.items[i]
+	topK.index[topK.items[i].Key] = i
+	topK.index[topK.items[j].Key] = j
+}
+
+func (topK *TopK) Push(x interface{}) {
+	item := x.(HeavyHitter)
+	topK.index[item.Key] = len(topK.items)
+	topK.items = append(topK.items, item)
+}
+
+func (topK *TopK) Pop() interface{} {
+	n := len(topK.items)
+	item := topK.items[n-1]
+	topK.items = topK.items[:n-1]
+	delete(topK.index, item.Key)
+	return item
+}
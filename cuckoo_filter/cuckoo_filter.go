@@ -0,0 +1,195 @@
+package cuckoo_filter
+
+const (
+	BUCKET_SIZE = 4   // broj otisaka u jednoj kofi
+	MAX_KICKS   = 500 // najvise premestanja pri jednom dodavanju
+	LOAD_FACTOR = 0.95
+)
+
+/*
+Cuckoo filter: svaki kljuc ima 16-bitni otisak koji se cuva u jednoj od dve kofe. Druga kofa
+se racuna iz prve i otiska (i2 = i1 ^ hash(otisak)), pa se otisak moze premestiti bez samog
+kljuca. Za razliku od bloom filtera, kljuc se moze obrisati, ali samo ako je ranije dodat, i
+to onoliko puta koliko je dodat. Otisak koji posle MAX_KICKS premestanja ne nadje mesto
+ostaje kao victim, a filter se smatra punim i dalja dodavanja ne uspevaju.
+*/
+type CuckooFilter struct {
+	buckets [][BUCKET_SIZE]uint16 // 0 je prazno mesto
+	mask    uint32
+	count   int
+	victim  victim
+	random  uint64 // stanje xorshift generatora za izbor otiska koji se premesta
+}
+
+type victim struct {
+	used        bool
+	index       uint32
+	fingerprint uint16
+}
+
+// Filter za najvise capacity kljuceva, broj kofa je stepen dvojke
+func NewCuckooFilter(capacity int) *CuckooFilter {
+	needed := int(float64(capacity)/(BUCKET_SIZE*LOAD_FACTOR)) + 1
+	numBuckets := 1
+	for numBuckets < needed {
+		numBuckets <<= 1
+	}
+	return &CuckooFilter{
+		buckets: make([][BUCKET_SIZE]uint16, numBuckets),
+		mask:    uint32(numBuckets - 1),
+		random:  0x2545f4914f6cdd1d,
+	}
+}
+
+// FNV-1a sa finalizerom iz MurmurHash3
+func hashKey(key string) uint64 {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= 1099511628211
+	}
+	return mix(hash)
+}
+
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+func (f *CuckooFilter) nextRandom() uint64 {
+	f.random ^= f.random << 13
+	f.random ^= f.random >> 7
+	f.random ^= f.random << 17
+	return f.random
+}
+
+func (f *CuckooFilter) alternate(index uint32, fingerprint uint16) uint32 {
+	return (index ^ uint32(mix(uint64(fingerprint)))) & f.mask
+}
+
+func (f *CuckooFilter) locate(key string) (uint16, uint32, uint32) {
+	hash := hashKey(key)
+	fingerprint := uint16(hash >> 48)
+	if fingerprint == 0 {
+		fingerprint = 1
+	}
+	i1 := uint32(hash) & f.mask
+	return fingerprint, i1, f.alternate(i1, fingerprint)
+}
+
+func (f *CuckooFilter) insertInto(index uint32, fingerprint uint16) bool {
+	bucket := &f.buckets[index]
+	for slot := range bucket {
+		if bucket[slot] == 0 {
+			bucket[slot] = fingerprint
+			return true
+		}
+	}
+	return false
+}
+
+func (f *CuckooFilter) deleteFrom(index uint32, fingerprint uint16) bool {
+	bucket := &f.buckets[index]
+	for slot := range bucket {
+		if bucket[slot] == fingerprint {
+			bucket[slot] = 0
+			return true
+		}
+	}
+	return false
+}
+
+func (f *CuckooFilter) bucketHas(index uint32, fingerprint uint16) bool {
+	for _, stored := range f.buckets[index] {
+		if stored == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// Dodaje kljuc, false znaci da je filter pun i da kljuc nije dodat
+func (f *CuckooFilter) Insert(key string) bool {
+	if f.victim.used {
+		return false
+	}
+	fingerprint, i1, i2 := f.locate(key)
+	if f.insertInto(i1, fingerprint) || f.insertInto(i2, fingerprint) {
+		f.count++
+		return true
+	}
+	index := i1
+	if f.nextRandom()&1 == 0 {
+		index = i2
+	}
+	for kick := 0; kick < MAX_KICKS; kick++ {
+		slot := f.nextRandom() % BUCKET_SIZE
+		fingerprint, f.buckets[index][slot] = f.buckets[index][slot], fingerprint
+		index = f.alternate(index, fingerprint)
+		if f.insertInto(index, fingerprint) {
+			f.count++
+			return true
+		}
+	}
+	// kljuc je dodat, ali je neki drugi otisak ostao bez mesta
+	f.victim = victim{true, index, fingerprint}
+	f.count++
+	return true
+}
+
+func (f *CuckooFilter) Contains(key string) bool {
+	fingerprint, i1, i2 := f.locate(key)
+	if f.bucketHas(i1, fingerprint) || f.bucketHas(i2, fingerprint) {
+		return true
+	}
+	return f.victim.used && f.victim.fingerprint == fingerprint &&
+		(f.victim.index == i1 || f.victim.index == i2)
+}
+
+// Brise jedno ranije dodavanje kljuca, false ako otisak kljuca nije nadjen
+func (f *CuckooFilter) Delete(key string) bool {
+	fingerprint, i1, i2 := f.locate(key)
+	if f.victim.used && f.victim.fingerprint == fingerprint && (f.victim.index == i1 || f.victim.index == i2) {
+		f.victim = victim{}
+		f.count--
+		return true
+	}
+	if f.deleteFrom(i1, fingerprint) || f.deleteFrom(i2, fingerprint) {
+		f.count--
+		// oslobodjeno mesto moze da primi victim-a
+		if f.victim.used {
+			v := f.victim
+			f.victim = victim{}
+			f.count--
+			f.insertFingerprint(v.index, v.fingerprint)
+		}
+		return true
+	}
+	return false
+}
+
+func (f *CuckooFilter) insertFingerprint(index uint32, fingerprint uint16) {
+	if f.insertInto(index, fingerprint) || f.insertInto(f.alternate(index, fingerprint), fingerprint) {
+		f.count++
+		return
+	}
+	f.victim = victim{true, index, fingerprint}
+	f.count++
+}
+
+// Broj kljuceva u filteru
+func (f *CuckooFilter) Count() int {
+	return f.count
+}
+
+func (f *CuckooFilter) Reset() {
+	for i := range f.buckets {
+		f.buckets[i] = [BUCKET_SIZE]uint16{}
+	}
+	f.count = 0
+	f.victim = victim{}
+}
@@ -0,0 +1,91 @@
+package cuckoo_filter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestInsertContainsDelete(t *testing.T) {
+	filter := NewCuckooFilter(1000)
+	for i := 0; i < 1000; i++ {
+		if !filter.Insert(fmt.Sprint("key", i)) {
+			t.Fatalf("insert of key%d failed below capacity", i)
+		}
+	}
+	if filter.Count() != 1000 {
+		t.Fatalf("count = %d, want 1000", filter.Count())
+	}
+	for i := 0; i < 1000; i += 2 {
+		if !filter.Delete(fmt.Sprint("key", i)) {
+			t.Fatalf("delete of key%d failed", i)
+		}
+	}
+	for i := 1; i < 1000; i += 2 {
+		if !filter.Contains(fmt.Sprint("key", i)) {
+			t.Fatalf("key%d lost after deleting other keys", i)
+		}
+	}
+	if filter.Count() != 500 {
+		t.Errorf("count = %d, want 500", filter.Count())
+	}
+	if filter.Delete("never added") {
+		t.Error("deleted a key that was never added")
+	}
+}
+
+// Pun filter cuva poslednji otisak bez mesta kao victim-a: svi dodati kljucevi se i dalje
+// nalaze, a posle brisanja victim se vraca u kofu i filter ponovo prima kljuceve
+func TestVictim(t *testing.T) {
+	filter := NewCuckooFilter(100)
+	var added []string
+	for i := 0; ; i++ {
+		key := fmt.Sprint("key", i)
+		if !filter.Insert(key) {
+			break
+		}
+		added = append(added, key)
+		if i > 10000 {
+			t.Fatal("filter never became full")
+		}
+	}
+	if !filter.victim.used {
+		t.Fatal("full filter has no victim")
+	}
+	for _, key := range added {
+		if !filter.Contains(key) {
+			t.Fatalf("%s lost when the filter became full", key)
+		}
+	}
+
+	// brisu se kljucevi dok se victim ne vrati u neku kofu
+	removed := 0
+	for filter.victim.used && removed < len(added) {
+		if !filter.Delete(added[removed]) {
+			t.Fatalf("delete of %s failed", added[removed])
+		}
+		removed++
+	}
+	if filter.victim.used {
+		t.Fatal("victim was never placed back")
+	}
+	for _, key := range added[removed:] {
+		if !filter.Contains(key) {
+			t.Fatalf("%s lost after the victim was placed back", key)
+		}
+	}
+	if filter.Count() != len(added)-removed {
+		t.Errorf("count = %d, want %d", filter.Count(), len(added)-removed)
+	}
+	if !filter.Insert("new key") {
+		t.Error("insert failed after the victim was placed back")
+	}
+}
+
+func TestReset(t *testing.T) {
+	filter := NewCuckooFilter(10)
+	filter.Insert("key")
+	filter.Reset()
+	if filter.Count() != 0 || filter.Contains("key") {
+		t.Error("reset filter still holds a key")
+	}
+}
@@ -0,0 +1,256 @@
+package ddsketch
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"projekat_nasp/config"
+	"sort"
+)
+
+const (
+	DDSKETCH_VERSION = 1
+	MAX_BINS         = 2048 // najvise kanti, kada ih ima vise spajaju se najmanje vrednosti
+	MIN_INDEXABLE    = 1e-9 // manje vrednosti se broje kao nula
+)
+
+/*
+DDSketch za kvantile nenegativnih vrednosti (velicine vrednosti, trajanja operacija). Vrednost x
+ide u kantu ceil(log_gamma(x)), gde je gamma = (1+a)/(1-a), pa je svaki procenjeni kvantil
+udaljen od stvarnog najvise a relativno (a = relativeAccuracy). Broj kanti raste samo sa
+logaritmom opsega vrednosti, a dve skice iste tacnosti se spajaju sabiranjem kanti.
+*/
+type DDSketch struct {
+	relativeAccuracy float64
+	gamma            float64
+	logGamma         float64
+	bins             map[int]uint64 // indeks kante -> broj vrednosti
+	zeroCount        uint64
+	count            uint64
+	sum              float64
+	min              float64
+	max              float64
+}
+
+// Nova skica, za tacnost van (0, 1) koristi se config.DDSKETCH_ACCURACY
+func NewDDSketch(relativeAccuracy float64) *DDSketch {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		relativeAccuracy = config.DDSKETCH_ACCURACY
+	}
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &DDSketch{
+		relativeAccuracy: relativeAccuracy,
+		gamma:            gamma,
+		logGamma:         math.Log(gamma),
+		bins:             make(map[int]uint64),
+		min:              math.Inf(1),
+		max:              math.Inf(-1),
+	}
+}
+
+func (s *DDSketch) RelativeAccuracy() float64 {
+	return s.relativeAccuracy
+}
+
+func (s *DDSketch) index(value float64) int {
+	return int(math.Ceil(math.Log(value) / s.logGamma))
+}
+
+// Vrednost kante je sredina njenog opsega (gamma^(i-1), gamma^i] u relativnom smislu
+func (s *DDSketch) binValue(index int) float64 {
+	return 2 * math.Pow(s.gamma, float64(index)) / (s.gamma + 1)
+}
+
+// Dodaje vrednost, negativne vrednosti se broje kao nula
+func (s *DDSketch) Add(value float64) {
+	if value < 0 || math.IsNaN(value) {
+		value = 0
+	}
+	if value < MIN_INDEXABLE {
+		s.zeroCount++
+	} else {
+		index := s.index(value)
+		if _, ok := s.bins[index]; !ok && len(s.bins) >= MAX_BINS {
+			s.collapseLowest()
+		}
+		s.bins[index]++
+	}
+	s.count++
+	s.sum += value
+	s.min = math.Min(s.min, value)
+	s.max = math.Max(s.max, value)
+}
+
+// Spaja dve kante sa najmanjim indeksima, pa tacnost gube samo najmanje vrednosti
+func (s *DDSketch) collapseLowest() {
+	lowest, second := math.MaxInt, math.MaxInt
+	for index := range s.bins {
+		if index < lowest {
+			lowest, second = index, lowest
+		} else if index < second {
+			second = index
+		}
+	}
+	if second == math.MaxInt {
+		return
+	}
+	s.bins[second] += s.bins[lowest]
+	delete(s.bins, lowest)
+}
+
+func (s *DDSketch) Count() uint64 {
+	return s.count
+}
+
+func (s *DDSketch) Sum() float64 {
+	return s.sum
+}
+
+func (s *DDSketch) Mean() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.sum / float64(s.count)
+}
+
+func (s *DDSketch) Min() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.min
+}
+
+func (s *DDSketch) Max() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.max
+}
+
+// Procena kvantila q iz [0, 1], za praznu skicu 0
+func (s *DDSketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	q = math.Max(0, math.Min(1, q))
+	rank := q * float64(s.count-1)
+	if rank < float64(s.zeroCount) {
+		return 0
+	}
+	indexes := s.sortedIndexes()
+	cumulative := s.zeroCount
+	for _, index := range indexes {
+		cumulative += s.bins[index]
+		if float64(cumulative) > rank {
+			return math.Max(s.min, math.Min(s.max, s.binValue(index)))
+		}
+	}
+	return s.max
+}
+
+func (s *DDSketch) sortedIndexes() []int {
+	indexes := make([]int, 0, len(s.bins))
+	for index := range s.bins {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// Dodaje vrednosti druge skice, tacnosti skica moraju biti iste
+func (s *DDSketch) Merge(other *DDSketch) error {
+	if other.gamma != s.gamma {
+		return errors.New("ddsketch: cannot merge sketches with different accuracy")
+	}
+	for index, count := range other.bins {
+		if _, ok := s.bins[index]; !ok && len(s.bins) >= MAX_BINS {
+			s.collapseLowest()
+		}
+		s.bins[index] += count
+	}
+	s.zeroCount += other.zeroCount
+	s.count += other.count
+	s.sum += other.sum
+	s.min = math.Min(s.min, other.min)
+	s.max = math.Max(s.max, other.max)
+	return nil
+}
+
+/*
+verzija | tacnost | broj vrednosti | broj nula | zbir | min | max | broj kanti | kante
+Realni brojevi su 8 bajtova (little-endian), ostali brojevi uvarint. Kante su sortirane, a
+indeks se cuva kao zigzag razlika u odnosu na prethodnu kantu, pa obicno staje u jedan bajt.
+*/
+func (s *DDSketch) Serialize() []byte {
+	data := binary.AppendUvarint(nil, DDSKETCH_VERSION)
+	data = binary.LittleEndian.AppendUint64(data, math.Float64bits(s.relativeAccuracy))
+	data = binary.AppendUvarint(data, s.count)
+	data = binary.AppendUvarint(data, s.zeroCount)
+	data = binary.LittleEndian.AppendUint64(data, math.Float64bits(s.sum))
+	data = binary.LittleEndian.AppendUint64(data, math.Float64bits(s.min))
+	data = binary.LittleEndian.AppendUint64(data, math.Float64bits(s.max))
+	indexes := s.sortedIndexes()
+	data = binary.AppendUvarint(data, uint64(len(indexes)))
+	previous := 0
+	for _, index := range indexes {
+		data = binary.AppendVarint(data, int64(index-previous))
+		data = binary.AppendUvarint(data, s.bins[index])
+		previous = index
+	}
+	return data
+}
+
+func Deserialize(data []byte) (*DDSketch, error) {
+	invalid := errors.New("ddsketch: invalid serialized sketch")
+	uvarint := func() (uint64, bool) {
+		value, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, false
+		}
+		data = data[n:]
+		return value, true
+	}
+	float := func() (float64, bool) {
+		if len(data) < 8 {
+			return 0, false
+		}
+		value := math.Float64frombits(binary.LittleEndian.Uint64(data))
+		data = data[8:]
+		return value, true
+	}
+
+	version, ok := uvarint()
+	if !ok || version != DDSKETCH_VERSION {
+		return nil, errors.New("ddsketch: unknown serialization version")
+	}
+	accuracy, ok := float()
+	if !ok || accuracy <= 0 || accuracy >= 1 {
+		return nil, invalid
+	}
+	s := NewDDSketch(accuracy)
+	var okCount, okZero, okSum, okMin, okMax bool
+	s.count, okCount = uvarint()
+	s.zeroCount, okZero = uvarint()
+	s.sum, okSum = float()
+	s.min, okMin = float()
+	s.max, okMax = float()
+	numBins, ok := uvarint()
+	if !(okCount && okZero && okSum && okMin && okMax && ok) || numBins > uint64(len(data)) {
+		return nil, invalid
+	}
+	index := 0
+	for i := uint64(0); i < numBins; i++ {
+		delta, n := binary.Varint(data)
+		if n <= 0 {
+			return nil, invalid
+		}
+		data = data[n:]
+		count, ok := uvarint()
+		if !ok {
+			return nil, invalid
+		}
+		index += int(delta)
+		s.bins[index] = count
+	}
+	return s, nil
+}
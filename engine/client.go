@@ -0,0 +1,97 @@
+package engine
+
+/*
+Pristup bazi u ime jednog klijenta. Iste operacije kao Engine, ali se svaka pre izvrsavanja
+naplacuje iz kofe klijenta prema vrsti operacije i broju bajtova (vidi Allow):
+  - GET: ReadWeight, a posle citanja jos po jedan token na svakih BytesPerToken procitanih bajtova
+  - PUT/MERGE: WriteWeight plus bajtovi kljuca i vrednosti
+  - DELETE: WriteWeight plus bajtovi kljuca
+  - PrefixScan: ScanWeight po kljucu trazene stranice, iz budzeta za citanja
+  - FindNearDuplicates: ReadWeight plus bajtovi upita
+  - komande nad strukturama (Execute): WriteWeight ili ReadWeight plus bajtovi argumenata
+
+Kada tokena nema, operacija se ne izvrsava i vraca *RateLimitError.
+*/
+type Client struct {
+	engine *Engine
+	name   string
+}
+
+func (engine *Engine) Client(name string) *Client {
+	return &Client{engine: engine, name: name}
+}
+
+func (client *Client) allow(budget string, cost int) error {
+	decision := client.engine.Allow(client.name, budget, cost)
+	if !decision.Allowed {
+		return &RateLimitError{Client: client.name, Budget: budget, Decision: decision}
+	}
+	return nil
+}
+
+func (client *Client) Get(key string) ([]byte, bool, error) {
+	err := client.allow(READ_BUDGET, readCost())
+	if err != nil {
+		return nil, false, err
+	}
+	value, found := client.engine.Get(key)
+	client.engine.charge(client.name, READ_BUDGET, bytesCost(len(key)+len(value)))
+	return value, found, nil
+}
+
+func (client *Client) Put(key string, value []byte) error {
+	err := client.allow(WRITE_BUDGET, writeCost(key, value))
+	if err != nil {
+		return err
+	}
+	return client.engine.Put(key, value)
+}
+
+func (client *Client) Delete(key string) error {
+	err := client.allow(WRITE_BUDGET, writeCost(key, nil))
+	if err != nil {
+		return err
+	}
+	return client.engine.Delete(key)
+}
+
+func (client *Client) Merge(key string, operand []byte) error {
+	err := client.allow(WRITE_BUDGET, writeCost(key, operand))
+	if err != nil {
+		return err
+	}
+	return client.engine.Merge(key, operand)
+}
+
+func (client *Client) PrefixScan(prefix string, pageNumber int, pageSize int) error {
+	err := client.allow(READ_BUDGET, scanCost(pageSize))
+	if err != nil {
+		return err
+	}
+	client.engine.PrefixScan(prefix, pageNumber, pageSize)
+	return nil
+}
+
+func (client *Client) FindNearDuplicates(query string, maxDistance int) ([]string, error) {
+	err := client.allow(READ_BUDGET, readCost()+bytesCost(len(query)))
+	if err != nil {
+		return nil, err
+	}
+	return client.engine.FindNearDuplicates(query, maxDistance), nil
+}
+
+func (client *Client) Execute(args []string) (string, error) {
+	size := 0
+	for _, arg := range args {
+		size += len(arg)
+	}
+	budget, cost := READ_BUDGET, readCost()+bytesCost(size)
+	if len(args) > 0 && IsWriteCommand(args[0]) {
+		budget, cost = WRITE_BUDGET, writeCost("", nil)+bytesCost(size)
+	}
+	err := client.allow(budget, cost)
+	if err != nil {
+		return "", err
+	}
+	return client.engine.Execute(args)
+}
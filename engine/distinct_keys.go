@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"math"
+	"projekat_nasp/hyperloglog"
+	"projekat_nasp/memTable"
+	"projekat_nasp/sstable"
+)
+
+// HyperLogLog++ svih kljuceva upisanih sa Put i Merge se cuva pod internim kljucem
+const WRITTEN_KEYS_HLL_KEY = memTable.INTERNAL_KEY_PREFIX + "keys/hll"
+
+/*
+Procena broja razlicitih kljuceva ikada upisanih sa Put ili Merge, ukljucujuci i one koji su
+kasnije obrisani. HyperLogLog++ se drzi u memoriji i menja pri svakom upisu, a u bazu se upisuje tek
+u Flush i Close, kao i stanje pracenja ucestalosti kljuceva.
+*/
+func (engine *Engine) KeyCardinality() float64 {
+	return engine.writtenKeys.Count()
+}
+
+func (engine *Engine) loadWrittenKeys() {
+	engine.writtenKeys = nil
+	if stored, found := engine.get(WRITTEN_KEYS_HLL_KEY); found {
+		if hll, err := hyperloglog.DeserializePlus(stored); err == nil {
+			engine.writtenKeys = hll
+		}
+	}
+	if engine.writtenKeys == nil {
+		engine.writtenKeys = newHll()
+	}
+}
+
+func (engine *Engine) saveWrittenKeys() {
+	engine.put(WRITTEN_KEYS_HLL_KEY, engine.writtenKeys.Serialize())
+}
+
+/*
+Procena broja razlicitih korisnickih kljuceva u opsegu [start, end), prazan end znaci bez
+gornje granice. Spajaju se HyperLogLog++ skice iz properties blokova SSTabela koje seku opseg
+(vidi sstable.DistinctKeys) i dodaju kljucevi iz memtabela, pa se kljucevi koji postoje u vise
+tabela broje jednom. Obrisani kljucevi se broje dok ih kompakcija ne ukloni, pa je procena
+gornja granica broja zivih kljuceva.
+*/
+func (engine *Engine) ApproxDistinct(start string, end string) (uint64, error) {
+	sketch := hyperloglog.NewHLLPlus(sstable.KEY_SKETCH_PRECISION)
+	err := sstable.DistinctKeys(start, end, sketch)
+	if err != nil {
+		return 0, err
+	}
+	for _, table := range engine.memtables.Sort() {
+		for _, entry := range table {
+			key := entry.GetKey()
+			if key >= start && (end == "" || key < end) && !memTable.IsInternalKey(key) {
+				sketch.Add(key)
+			}
+		}
+	}
+	return uint64(math.Round(sketch.Count())), nil
+}
+
+// Procena broja razlicitih korisnickih kljuceva sa datim prefiksom, gornja granica kao kod ApproxDistinct
+func (engine *Engine) Count(prefix string) (uint64, error) {
+	return engine.ApproxDistinct(prefix, prefixEnd(prefix))
+}
+
+// Najmanji kljuc veci od svih kljuceva sa prefiksom, prazan ako takav ne postoji
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,258 @@
+package engine
+
+import (
+	"errors"
+	"projekat_nasp/cache"
+	"projekat_nasp/config"
+	"projekat_nasp/countMinSketch"
+	"projekat_nasp/ddsketch"
+	"projekat_nasp/hyperloglog"
+	"projekat_nasp/memTable"
+	"projekat_nasp/sstable"
+	"projekat_nasp/token_bucket"
+	"projekat_nasp/wal"
+	"strings"
+	"time"
+)
+
+/*
+Engine objedinjuje putanju upisa (WAL -> memtabele -> SSTabele) i putanju citanja
+(memtabele -> cache -> SSTabele) iza jednog API-ja.
+*/
+type Engine struct {
+	wal       *wal.Wal
+	memtables memTable.MemTablesManager
+	cache     *cache.Cache
+	buckets   map[string]*token_bucket.TokenBucket // ucitane kofe klijenata, vidi Allow
+	sketch    *countMinSketch.CountMinSketch       // ucestalost kljuceva, vidi track
+	topK      *countMinSketch.TopK
+	// raspodele velicina vrednosti i trajanja operacija, vidi Stats
+	valueSizes *ddsketch.DDSketch
+	getLatency *ddsketch.DDSketch
+	putLatency *ddsketch.DDSketch
+	// kofe promenjene od poslednjeg cuvanja, upisuju se u Flush i Close
+	dirtyBuckets map[string]bool
+	// razliciti kljucevi upisani sa Put i Merge, vidi KeyCardinality
+	writtenKeys *hyperloglog.HLLPlus
+}
+
+// Pravi engine prema config.GlobalConfig i vraca u memtabele sve sto je ostalo u WAL-u
+func NewEngine() *Engine {
+	engine := &Engine{
+		wal:       wal.NewWal(),
+		memtables: newMemTables(),
+		cache:     newCache(),
+		buckets:   make(map[string]*token_bucket.TokenBucket),
+	}
+	engine.dirtyBuckets = make(map[string]bool)
+	engine.wal.Recovery(&engine.memtables)
+	engine.loadHotKeys()
+	engine.loadStats()
+	engine.loadWrittenKeys()
+	return engine
+}
+
+func newCache() *cache.Cache {
+	capacity := config.GlobalConfig.CacheCapacity
+	if capacity <= 0 {
+		capacity = config.CACHE_CAP
+	}
+	return cache.NewCache(capacity)
+}
+
+func newMemTables() memTable.MemTablesManager {
+	var memtables memTable.MemTablesManager
+	switch config.GlobalConfig.StructureType {
+	case "hashmap":
+		memtables = memTable.InitMemTablesHash(config.GlobalConfig.MaxTables, uint64(config.GlobalConfig.MemtableSize))
+	case "btree":
+		memtables = memTable.InitMemTablesBTree(config.GlobalConfig.MaxTables, uint64(config.GlobalConfig.MemtableSize), uint8(config.GlobalConfig.BTreeOrder))
+	default:
+		memtables = memTable.InitMemTablesSkipList(config.GlobalConfig.MaxTables, uint64(config.GlobalConfig.MemtableSize), config.GlobalConfig.SkipListHeight)
+	}
+	return memtables
+}
+
+// Greska za korisnicke operacije nad kljucem sa prefiksom memTable.INTERNAL_KEY_PREFIX
+var ErrReservedKey = errors.New("engine: key uses the reserved internal prefix")
+
+func (engine *Engine) Put(key string, value []byte) error {
+	if memTable.IsInternalKey(key) {
+		return ErrReservedKey
+	}
+	start := time.Now()
+	engine.put(key, value)
+	engine.track(key)
+	engine.writtenKeys.Add(key)
+	engine.updateSimHash(key, value, true)
+	engine.valueSizes.Add(float64(len(value)))
+	engine.putLatency.Add(microseconds(start))
+	return nil
+}
+
+// Interni kljucevi ne ulaze u kes, da ne bi izbacivali korisnicke podatke
+func (engine *Engine) put(key string, value []byte) {
+	walEntry := engine.wal.Write(key, value, 0)
+	engine.addToMemTable(memTable.NewMemTableEntry(key, value, 0, walEntry.Timestamp))
+	if !memTable.IsInternalKey(key) {
+		engine.cache.AddItem(key, string(value))
+	}
+}
+
+func (engine *Engine) Delete(key string) error {
+	if memTable.IsInternalKey(key) {
+		return ErrReservedKey
+	}
+	engine.delete(key)
+	engine.track(key)
+	engine.updateSimHash(key, nil, false)
+	return nil
+}
+
+func (engine *Engine) delete(key string) {
+	walEntry := engine.wal.Write(key, nil, 1)
+	engine.addToMemTable(memTable.NewMemTableEntry(key, nil, 1, walEntry.Timestamp))
+	engine.cache.DeleteByKey(key)
+}
+
+/*
+Upisuje operand koji se operatorom iz config.MergeOperator spaja sa trenutnom vrednoscu
+kljuca (npr. uvecanje brojaca), bez citanja te vrednosti. Operand se cuva kao poseban zapis
+u WAL-u i memtabeli, a spaja se tek pri citanju i tokom kompakcije.
+*/
+func (engine *Engine) Merge(key string, operand []byte) error {
+	if memTable.IsInternalKey(key) {
+		return ErrReservedKey
+	}
+	walEntry := engine.wal.Write(key, operand, memTable.MERGE_OPERAND)
+	engine.addToMemTable(memTable.NewMemTableEntry(key, operand, memTable.MERGE_OPERAND, walEntry.Timestamp))
+	engine.cache.DeleteByKey(key)
+	engine.track(key)
+	engine.writtenKeys.Add(key)
+	engine.updateSimHashAfterMerge(key)
+	return nil
+}
+
+// Vraca poslednju vrednost kljuca, obrisani i interni kljucevi se ne vracaju
+func (engine *Engine) Get(key string) ([]byte, bool) {
+	if memTable.IsInternalKey(key) {
+		return nil, false
+	}
+	start := time.Now()
+	engine.track(key)
+	value, found := engine.get(key)
+	engine.getLatency.Add(microseconds(start))
+	return value, found
+}
+
+func (engine *Engine) get(key string) ([]byte, bool) {
+	cached := !memTable.IsInternalKey(key)
+	found, entry := engine.memtables.Find(key)
+	if found && entry.GetTombstone() != memTable.MERGE_OPERAND {
+		if entry.GetTombstone() == 1 {
+			return nil, false
+		}
+		if cached {
+			engine.cache.AddItem(key, string(entry.GetValue()))
+		}
+		return entry.GetValue(), true
+	}
+
+	if !found && cached {
+		found, value := engine.cache.GetByKey(key)
+		if found {
+			return []byte(value.(string)), true
+		}
+	}
+
+	// operand iz memtabele je vec spojen sa svim starijim zapisima u memtabelama,
+	// pa se ostatak istorije kljuca trazi samo u SSTabelama
+	var versions []memTable.MemTableEntry
+	if found {
+		versions = append(versions, entry)
+	}
+	versions = append(versions, sstable.SearchVersions(key)...)
+	if len(versions) == 0 {
+		return nil, false
+	}
+	newest := versions[0]
+	if newest.GetTombstone() == memTable.MERGE_OPERAND {
+		newest = memTable.MergeVersions(versions)
+	} else if newest.GetTombstone() == 1 {
+		return nil, false
+	}
+	if cached {
+		engine.cache.AddItem(key, string(newest.GetValue()))
+	}
+	return newest.GetValue(), true
+}
+
+// Prefix scan kroz SSTabele, tabele cije opseg kljuceva ili prefiksni filter iskljucuju prefix se preskacu
+func (engine *Engine) PrefixScan(prefix string, pageNumber int, pageSize int) {
+	engine.memtables.PrefixScan(prefix, pageNumber, pageSize, sstable.PrefixScanTables(prefix))
+}
+
+/*
+Interni kljucevi sa prefiksom iz memtabela i SSTabela, bez ponavljanja. Medju njima mogu biti
+i obrisani kljucevi, pa se vrednost svakog cita sa get.
+*/
+func (engine *Engine) internalKeys(prefix string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	add := func(key string) {
+		if strings.HasPrefix(key, prefix) && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for _, table := range engine.memtables.Sort() {
+		for _, entry := range table {
+			add(entry.GetKey())
+		}
+	}
+	// prefiksni filter tabela ne sadrzi interne kljuceve, pa se tabele biraju samo po opsegu
+	end := prefixEnd(prefix)
+	for _, table := range sstable.ListTables() {
+		if table.Properties.NumEntries == 0 || table.Properties.LargestKey < prefix ||
+			(end != "" && table.Properties.SmallestKey >= end) {
+			continue
+		}
+		for _, entry := range sstable.FindByKey([]string{prefix}, table.Path, false) {
+			add(entry.GetKey())
+		}
+	}
+	return keys
+}
+
+func (engine *Engine) addToMemTable(entry memTable.MemTableEntry) {
+	full, sizeToDelete := engine.memtables.Add(entry)
+	if full != nil {
+		sstable.FlushMemTable(full)
+		engine.wal.DeleteBytesFromFiles(sizeToDelete)
+	}
+}
+
+// Upisuje sve memtabele u SSTabele, od najstarije ka najnovijoj, i brise njihov deo WAL-a.
+// Pre toga se u memtabele upisuje stanje pracenja ucestalosti kljuceva, skice kvantila,
+// promenjene kofe rate limiter-a i HyperLogLog upisanih kljuceva.
+func (engine *Engine) Flush() {
+	engine.saveHotKeys()
+	engine.saveStats()
+	engine.saveBuckets()
+	engine.saveWrittenKeys()
+	flushed, sizeToDelete := engine.memtables.FlushAll()
+	for _, data := range flushed {
+		sstable.FlushMemTable(data)
+	}
+	if sizeToDelete != 0 {
+		engine.wal.DeleteBytesFromFiles(sizeToDelete)
+	}
+}
+
+// Cuva stanje engine-a koje se drzi u memoriji, poziva se pre izlaska iz programa
+func (engine *Engine) Close() {
+	engine.saveHotKeys()
+	engine.saveStats()
+	engine.saveBuckets()
+	engine.saveWrittenKeys()
+}
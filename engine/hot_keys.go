@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/gob"
+	"projekat_nasp/config"
+	"projekat_nasp/countMinSketch"
+	"projekat_nasp/memTable"
+	"time"
+)
+
+// Stanje pracenja ucestalosti kljuceva se cuva pod internim kljucevima
+const (
+	HOT_KEYS_SKETCH_KEY = memTable.INTERNAL_KEY_PREFIX + "cms/sketch"
+	HOT_KEYS_TOP_K_KEY  = memTable.INTERNAL_KEY_PREFIX + "cms/top_k"
+)
+
+/*
+Svako korisnicko citanje i upis povecava ucestalost kljuca u Count-Min Sketch-u, a procena
+se prosledjuje TopK strukturi koja drzi HotKeys najcesce koriscenih kljuceva. Ti kljucevi
+se pinuju u kesu, pa ih LRU ne izbacuje zbog kljuceva koji se koriste retko. Brojaci se
+polove svakih CmsDecayInterval sekundi, pa kljuc koji se vise ne koristi vremenom ispada.
+*/
+func (engine *Engine) track(key string) {
+	if shift := engine.sketch.DecayIfDue(time.Now()); shift > 0 {
+		engine.topK.Halve(shift)
+	}
+	engine.sketch.AddKey(key)
+	if engine.topK.Update(key, engine.sketch.FindKeyFrequency(key)) {
+		engine.pinHotKeys()
+	}
+}
+
+// Najcesce koriscenih k kljuceva sa procenom ucestalosti, od najcesceg
+func (engine *Engine) HotKeys(k int) []countMinSketch.HeavyHitter {
+	return engine.topK.Items(k)
+}
+
+// Procena broja citanja i upisa kljuca, nikad manja od stvarnog broja
+func (engine *Engine) KeyFrequency(key string) uint {
+	return engine.sketch.FindKeyFrequency(key)
+}
+
+// U kesu se pinuje najvise pola njegovog kapaciteta, da bi ostalo mesta za ostale kljuceve
+func (engine *Engine) pinHotKeys() {
+	hot := engine.topK.Items(engine.cache.MaxLength / 2)
+	keys := make([]string, len(hot))
+	for i, hitter := range hot {
+		keys[i] = hitter.Key
+	}
+	engine.cache.SetPinned(keys)
+}
+
+func hotKeysCapacity() int {
+	capacity := config.GlobalConfig.HotKeys
+	if capacity <= 0 {
+		capacity = config.HOT_KEYS
+	}
+	return capacity
+}
+
+func newSketch() *countMinSketch.CountMinSketch {
+	epsilon := config.GlobalConfig.CmsEpsilon
+	if epsilon <= 0 {
+		epsilon = config.CMS_EPSILON
+	}
+	delta := config.GlobalConfig.CmsDelta
+	if delta <= 0 {
+		delta = config.CMS_DELTA
+	}
+	sketch := countMinSketch.NewCountMinSketch(epsilon, 1-delta)
+	sketch.Conservative = config.GlobalConfig.CmsConservative
+	if config.GlobalConfig.CmsDecayInterval > 0 {
+		sketch.DecayInterval = time.Duration(config.GlobalConfig.CmsDecayInterval * float64(time.Second))
+	}
+	return sketch
+}
+
+// Ucitava sketch i heavy hitter-e sacuvane u bazi, ili pravi prazne
+func (engine *Engine) loadHotKeys() {
+	engine.sketch = nil
+	if stored, found := engine.get(HOT_KEYS_SKETCH_KEY); found {
+		if sketch, err := countMinSketch.Deserialize(stored); err == nil {
+			engine.sketch = sketch
+		}
+	}
+	if engine.sketch == nil {
+		engine.sketch = newSketch()
+	}
+
+	engine.topK = countMinSketch.NewTopK(hotKeysCapacity())
+	if stored, found := engine.get(HOT_KEYS_TOP_K_KEY); found {
+		var hitters []countMinSketch.HeavyHitter
+		if gob.NewDecoder(bytes.NewReader(stored)).Decode(&hitters) == nil {
+			for _, hitter := range hitters {
+				engine.topK.Update(hitter.Key, hitter.Count)
+			}
+		}
+	}
+	engine.pinHotKeys()
+}
+
+// Upisuje sketch i heavy hitter-e u bazu kao interne kljuceve
+func (engine *Engine) saveHotKeys() {
+	engine.put(HOT_KEYS_SKETCH_KEY, engine.sketch.Serialize())
+	var hitters bytes.Buffer
+	if gob.NewEncoder(&hitters).Encode(engine.topK.Items(-1)) == nil {
+		engine.put(HOT_KEYS_TOP_K_KEY, hitters.Bytes())
+	}
+}
@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"errors"
+	"projekat_nasp/lsm_tree"
+	"projekat_nasp/sstable"
+	"sort"
+	"time"
+)
+
+/*
+Ucitava tabele napravljene van baze bez prolaska kroz WAL i memtabele.
+Sve tabele se prvo provere, a tek kada su sve pripremljene postaju vidljive. Ako bilo
+koji korak ne uspe, nijedna tabela ne ostaje u bazi.
+
+Svi zapisi ucitane tabele dobijaju isti timestamp (vreme ucitavanja), pa su noviji od svega
+sto je do tada upisano. Tabela se smesta na najdublji nivo do kog se ne preklapa ni sa jednom
+postojecom tabelom, tako da pretraga koja staje na prvom nivou sa kljucem i dalje vraca najnoviju vrednost.
+*/
+func (engine *Engine) IngestFiles(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	var tables []*sstable.ExternalTable
+	for _, path := range paths {
+		table, err := sstable.ValidateExternalTable(path)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, table)
+	}
+
+	// memtabele su novije od svega na disku, pa se kljucevi iz njih moraju upisati pre ucitanih tabela
+	if engine.memTablesOverlap(tables) {
+		engine.Flush()
+	}
+
+	// redosled u paths odredjuje koja je tabela novija ako zavrse na istom nivou
+	levels := ingestLevels(tables)
+	timestamp := uint64(time.Now().Unix())
+	for i, table := range tables {
+		err := table.Prepare(levels[i], timestamp)
+		if err != nil {
+			rollback(tables[:i])
+			return err
+		}
+	}
+	for _, table := range tables {
+		err := table.Commit()
+		if err != nil {
+			rollback(tables)
+			return errors.New("ingest of " + table.Source + " failed: " + err.Error())
+		}
+	}
+
+	// vrednosti u kesu su sada zastarele
+	for key := range engine.cache.MapItems {
+		for _, table := range tables {
+			if table.Properties.MayContain(key) {
+				engine.cache.DeleteByKey(key)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func rollback(tables []*sstable.ExternalTable) {
+	for _, table := range tables {
+		table.Rollback()
+	}
+}
+
+func (engine *Engine) memTablesOverlap(tables []*sstable.ExternalTable) bool {
+	for _, data := range engine.memtables.Sort() {
+		for _, entry := range data {
+			key := entry.GetKey()
+			for _, table := range tables {
+				if table.Properties.MayContain(key) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Za svaku tabelu bira najdublji nivo takav da se ni na jednom nivou iznad njega, ni na njemu,
+// ne nalazi tabela koja se preklapa sa njom. Ako se ucitane tabele preklapaju medjusobno
+// sve idu na nivo 0, jedini na kom se tabele smeju preklapati, gde se razresavaju po rednom broju.
+func ingestLevels(tables []*sstable.ExternalTable) []int {
+	result := make([]int, len(tables))
+	for i := range result {
+		result[i] = sstable.FLUSH_LEVEL
+	}
+	if overlapEachOther(tables) {
+		return result
+	}
+
+	bottom := lsm_tree.BottomLevel()
+	levels, byLevel := sstable.GroupByLevel(sstable.ListTables())
+
+	for i, table := range tables {
+		for lvl := sstable.FLUSH_LEVEL; lvl <= bottom; lvl++ {
+			if levelOverlaps(byLevel[lvl], table.Properties) {
+				break
+			}
+			result[i] = lvl
+		}
+		// tabele ispod poslednjeg nivoa (npr. ako je MaxLevels smanjen) takodje moraju biti ispod
+		for _, lvl := range levels {
+			if lvl > bottom && levelOverlaps(byLevel[lvl], table.Properties) {
+				result[i] = sstable.FLUSH_LEVEL
+			}
+		}
+	}
+	return result
+}
+
+func levelOverlaps(tables []sstable.TableInfo, props *sstable.TableProperties) bool {
+	for _, table := range tables {
+		if table.Properties.Overlaps(props.SmallestKey, props.LargestKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func overlapEachOther(tables []*sstable.ExternalTable) bool {
+	sorted := make([]*sstable.ExternalTable, len(tables))
+	copy(sorted, tables)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Properties.SmallestKey < sorted[j].Properties.SmallestKey
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1].Properties.LargestKey >= sorted[i].Properties.SmallestKey {
+			return true
+		}
+	}
+	return false
+}
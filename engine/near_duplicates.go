@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"projekat_nasp/config"
+	"projekat_nasp/memTable"
+	"projekat_nasp/simhash"
+	"sort"
+	"strings"
+)
+
+// Otisci i trake indeksa skoro istih vrednosti se cuvaju pod internim kljucevima
+const (
+	SIMHASH_FINGERPRINT_PREFIX = memTable.INTERNAL_KEY_PREFIX + "simhash/fp/"
+	SIMHASH_BAND_PREFIX        = memTable.INTERNAL_KEY_PREFIX + "simhash/band/"
+)
+
+/*
+Indeks skoro istih vrednosti (LSH nad SimHash otiscima). Vrednosti kljuceva sa nekim od
+prefiksa iz SimHashPrefixes se pri upisu pretvaraju u 64-bitni otisak, koji se deli na
+SimHashBands traka. Za svaku traku postoji kofa sa kljucevima ciji otisak ima istu vrednost
+te trake, a svaki clan kofe je poseban interni kljuc sa zajednickim prefiksom kofe. Kandidati
+za upit su kljucevi iz kofa upita i nalaze se pretragom po prefiksu. Dva otiska na rastojanju manjem od
+broja traka se sigurno poklapaju u bar jednoj traci, pa se za maxDistance < SimHashBands
+nalaze svi slicni kljucevi, a za vece rastojanje samo oni koji dele neku traku. Kofe su
+odvojene po broju traka, pa posle promene SimHashBands vrednosti treba ponovo upisati.
+*/
+func simHashIndexed(key string) bool {
+	for _, prefix := range config.GlobalConfig.SimHashPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Broj traka mora deliti 64, inace se koristi podrazumevani
+func simHashBands() int {
+	bands := config.GlobalConfig.SimHashBands
+	if bands <= 0 || bands > 64 || 64%bands != 0 {
+		bands = config.SIMHASH_BANDS
+	}
+	return bands
+}
+
+/*
+Podesavanja SimHash-a iz konfiguracije: SimHashTokenizer je "words", "ngrams" (n-grami
+karaktera) ili "shingles" (nizovi reci). Stop reci se porede sa tokenima, pa se engleske
+podrazumevaju samo za reci, a SimHashStopwords ih zamenjuje za svaki tokenizer.
+Otisci koji su vec u indeksu se ne preracunavaju, pa posle promene treba ponovo upisati.
+*/
+func simHashOptions() simhash.Options {
+	options := simhash.DefaultOptions()
+	n := config.GlobalConfig.SimHashNGram
+	if n <= 0 {
+		n = config.SIMHASH_NGRAM
+	}
+	switch config.GlobalConfig.SimHashTokenizer {
+	case "ngrams":
+		options = simhash.Options{Tokenizer: simhash.CharNGramTokenizer{N: n}}
+	case "shingles":
+		options = simhash.Options{Tokenizer: simhash.ShingleTokenizer{N: n}}
+	}
+	if config.GlobalConfig.SimHashStopwords != nil {
+		options.Stopwords = make(map[string]bool)
+		for _, word := range config.GlobalConfig.SimHashStopwords {
+			options.Stopwords[strings.ToLower(word)] = true
+		}
+	}
+	return options
+}
+
+// Prefiksi kofa otiska, po jedan za svaku traku. Clan kofe je kljuc prefiks+kljuc.
+func bucketPrefixes(fingerprint uint64) []string {
+	bands := simHashBands()
+	width := 64 / bands
+	prefixes := make([]string, bands)
+	for band := 0; band < bands; band++ {
+		value := fingerprint >> (band * width)
+		if width < 64 {
+			value &= 1<<width - 1
+		}
+		prefixes[band] = fmt.Sprintf("%s%d/%d/%x/", SIMHASH_BAND_PREFIX, bands, band, value)
+	}
+	return prefixes
+}
+
+func (engine *Engine) fingerprint(key string) (uint64, bool) {
+	stored, found := engine.get(SIMHASH_FINGERPRINT_PREFIX + key)
+	if !found || len(stored) != 8 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(stored), true
+}
+
+/*
+Upisuje otisak kljuca i clanstvo u kofama njegovih traka. Svaki clan kofe je poseban interni
+kljuc, pa upis menja najvise dva kljuca po traci, bez obzira na velicinu kofe. Trake cija se
+vrednost nije promenila se ne diraju.
+*/
+func (engine *Engine) indexSimHash(key string, value []byte) {
+	fingerprint := simhash.Fingerprint(string(value), simHashOptions())
+	old, found := engine.fingerprint(key)
+	if found && old == fingerprint {
+		return
+	}
+	var oldPrefixes []string
+	if found {
+		oldPrefixes = bucketPrefixes(old)
+	}
+	stored := binary.LittleEndian.AppendUint64(nil, fingerprint)
+	for band, prefix := range bucketPrefixes(fingerprint) {
+		if band < len(oldPrefixes) && oldPrefixes[band] == prefix {
+			continue
+		}
+		if band < len(oldPrefixes) {
+			engine.delete(oldPrefixes[band] + key)
+		}
+		engine.put(prefix+key, stored)
+	}
+	engine.put(SIMHASH_FINGERPRINT_PREFIX+key, stored)
+}
+
+// Uklanja kljuc iz indeksa pri brisanju kljuca
+func (engine *Engine) unindexSimHash(key string) {
+	old, found := engine.fingerprint(key)
+	if !found {
+		return
+	}
+	for _, prefix := range bucketPrefixes(old) {
+		engine.delete(prefix + key)
+	}
+	engine.delete(SIMHASH_FINGERPRINT_PREFIX + key)
+}
+
+// Azurira indeks posle upisa ili brisanja kljuca, value je nova vrednost ako je found
+func (engine *Engine) updateSimHash(key string, value []byte, found bool) {
+	if !simHashIndexed(key) {
+		return
+	}
+	if !found {
+		engine.unindexSimHash(key)
+		return
+	}
+	engine.indexSimHash(key, value)
+}
+
+// Posle Merge-a nova vrednost nije poznata bez citanja kljuca
+func (engine *Engine) updateSimHashAfterMerge(key string) {
+	if !simHashIndexed(key) {
+		return
+	}
+	value, found := engine.get(key)
+	engine.updateSimHash(key, value, found)
+}
+
+/*
+Kljucevi cija je vrednost na Hamming rastojanju najvise maxDistance od upita, sortirani po
+rastojanju. Ako je query indeksiran kljuc trazi se prema njegovoj vrednosti (i on sam se ne
+vraca), a inace se query smatra tekstom.
+*/
+func (engine *Engine) FindNearDuplicates(query string, maxDistance int) []string {
+	if fingerprint, found := engine.fingerprint(query); found {
+		return engine.nearDuplicates(fingerprint, maxDistance, query)
+	}
+	return engine.FindNearDuplicatesOfText(query, maxDistance)
+}
+
+func (engine *Engine) FindNearDuplicatesOfText(text string, maxDistance int) []string {
+	return engine.nearDuplicates(simhash.Fingerprint(text, simHashOptions()), maxDistance, "")
+}
+
+func (engine *Engine) nearDuplicates(fingerprint uint64, maxDistance int, exclude string) []string {
+	distances := make(map[string]int)
+	for _, prefix := range bucketPrefixes(fingerprint) {
+		for _, member := range engine.internalKeys(prefix) {
+			candidate := strings.TrimPrefix(member, prefix)
+			if _, seen := distances[candidate]; seen || candidate == exclude {
+				continue
+			}
+			// clan kofe moze biti obrisan ili zastareo, vazi samo njegova poslednja verzija
+			stored, found := engine.get(member)
+			if !found || len(stored) != 8 {
+				continue
+			}
+			distances[candidate] = simhash.Distance(fingerprint, binary.LittleEndian.Uint64(stored))
+		}
+	}
+	var keys []string
+	for key, distance := range distances {
+		if distance <= maxDistance {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if distances[keys[i]] != distances[keys[j]] {
+			return distances[keys[i]] < distances[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"os"
+	"projekat_nasp/config"
+	"reflect"
+	"testing"
+)
+
+// Engine pise u data/ i logs/ tekuceg direktorijuma, pa se test izvrsava u privremenom
+func newTestEngine(t *testing.T) *Engine {
+	dir := t.TempDir()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+	for _, path := range []string{"data/sstable", "data/wal", "logs"} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config.GlobalConfig = *config.NewConfig("")
+	config.GlobalConfig.MemtableSize = 1000
+	config.GlobalConfig.SimHashPrefixes = []string{"doc/"}
+	return NewEngine()
+}
+
+// Otisci koji se razlikuju u jednom bitu dele sve trake osim one u kojoj je taj bit
+func TestBucketPrefixesShareUnchangedBands(t *testing.T) {
+	config.GlobalConfig = *config.NewConfig("")
+	a := bucketPrefixes(0x0123456789abcdef)
+	b := bucketPrefixes(0x0123456789abcdef ^ 1<<40)
+	if len(a) != config.SIMHASH_BANDS {
+		t.Fatalf("got %d bands, want %d", len(a), config.SIMHASH_BANDS)
+	}
+	shared := 0
+	for i := range a {
+		if a[i] == b[i] {
+			shared++
+		}
+	}
+	if shared != len(a)-1 {
+		t.Errorf("fingerprints share %d bands, want %d", shared, len(a)-1)
+	}
+}
+
+func TestNearDuplicates(t *testing.T) {
+	engine := newTestEngine(t)
+	// interni kljucevi indeksa moraju ostati vidljivi i kada tabele imaju prefiksni filter
+	config.GlobalConfig.PrefixExtractor = "fixed"
+	engine.Put("doc/a", []byte("the quick brown fox jumps over the lazy dog today"))
+	engine.Put("doc/b", []byte("the quick brown fox jumps over the lazy dog today!"))
+	engine.Put("doc/c", []byte("completely different text about databases and trees"))
+	engine.Put("other", []byte("the quick brown fox jumps over the lazy dog today"))
+
+	want := []string{"doc/b"}
+	if got := engine.FindNearDuplicates("doc/a", 10); !reflect.DeepEqual(got, want) {
+		t.Fatalf("before flush: got %v, want %v", got, want)
+	}
+	engine.Flush()
+	if got := engine.FindNearDuplicates("doc/a", 10); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after flush: got %v, want %v", got, want)
+	}
+
+	// novi upis menja kofe kljuca, a brisanje ga uklanja iz indeksa
+	engine.Put("doc/b", []byte("completely different text about databases and trees!"))
+	if got := engine.FindNearDuplicates("doc/c", 10); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after overwrite: got %v, want %v", got, want)
+	}
+	if got := engine.FindNearDuplicates("doc/a", 10); len(got) != 0 {
+		t.Fatalf("overwritten key still found: %v", got)
+	}
+	engine.Delete("doc/b")
+	if got := engine.FindNearDuplicatesOfText("completely different text about databases and trees", 10); !reflect.DeepEqual(got, []string{"doc/c"}) {
+		t.Fatalf("after delete: got %v, want [doc/c]", got)
+	}
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"fmt"
+	"projekat_nasp/config"
+	"projekat_nasp/memTable"
+	"projekat_nasp/token_bucket"
+)
+
+// Kofe klijenata se cuvaju pod internim kljucem TOKEN_BUCKET_PREFIX + budzet + "/" + ime klijenta
+const TOKEN_BUCKET_PREFIX = memTable.INTERNAL_KEY_PREFIX + "token_bucket/"
+
+// Budzeti klijenta, citanja i upisi se placaju iz odvojenih kofa
+const (
+	READ_BUDGET  = "read"
+	WRITE_BUDGET = "write"
+)
+
+// Greska kada klijent nema dovoljno tokena za zahtev
+type RateLimitError struct {
+	Client   string
+	Budget   string
+	Decision token_bucket.Decision
+}
+
+func (err *RateLimitError) Error() string {
+	return fmt.Sprintf("engine: %s budget of client %s exhausted, retry after %v", err.Budget, err.Client, err.Decision.RetryAfter)
+}
+
+/*
+Uzima cost tokena iz kofe klijenta (korisnika, tenanta...) za dati budzet. Kofa za citanja ima
+ReadTokenNumber, a kofa za upise WriteTokenNumber tokena (TokenNumber ako nisu zadati) i puni
+se svakih TokenRefreshTime sekundi. Kofe se drze u memoriji, a promenjene kofe se upisuju u
+bazu kao interni kljucevi tek u Flush i Close, pa zahtev ne pravi dodatni upis. Posle ponovnog
+pokretanja kofa klijenta se ucitava iz baze kada se on prvi put pojavi. Kada je zahtev odbijen,
+Decision sadrzi i vreme posle kog ga vredi ponoviti.
+*/
+func (engine *Engine) Allow(client string, budget string, cost int) token_bucket.Decision {
+	bucket := engine.bucket(client, budget)
+	decision := bucket.Take(cost)
+	engine.dirtyBuckets[budget+"/"+client] = true
+	return decision
+}
+
+// Naplacuje trosak poznat tek posle izvrsenog zahteva (npr. procitane bajtove), bez provere.
+// Kao i Allow menja samo kofu u memoriji, pa citanje ostaje bez upisa.
+func (engine *Engine) charge(client string, budget string, cost int) {
+	if cost <= 0 {
+		return
+	}
+	engine.bucket(client, budget).Charge(cost)
+	engine.dirtyBuckets[budget+"/"+client] = true
+}
+
+func (engine *Engine) bucket(client string, budget string) *token_bucket.TokenBucket {
+	name := budget + "/" + client
+	if bucket, ok := engine.buckets[name]; ok {
+		return bucket
+	}
+	var bucket *token_bucket.TokenBucket
+	if stored, found := engine.get(TOKEN_BUCKET_PREFIX + name); found {
+		bucket, _ = token_bucket.DecodeTokenBucket(stored)
+	}
+	if bucket == nil {
+		bucket = newTokenBucket(budget)
+	}
+	engine.buckets[name] = bucket
+	return bucket
+}
+
+// Upisuje u bazu kofe promenjene od prethodnog cuvanja
+func (engine *Engine) saveBuckets() {
+	for name := range engine.dirtyBuckets {
+		engine.put(TOKEN_BUCKET_PREFIX+name, engine.buckets[name].Encode())
+	}
+	engine.dirtyBuckets = make(map[string]bool)
+}
+
+func newTokenBucket(budget string) *token_bucket.TokenBucket {
+	tokens := config.GlobalConfig.ReadTokenNumber
+	if budget == WRITE_BUDGET {
+		tokens = config.GlobalConfig.WriteTokenNumber
+	}
+	if tokens <= 0 {
+		tokens = config.GlobalConfig.TokenNumber
+	}
+	if tokens <= 0 {
+		tokens = config.TOKEN_NUMBER
+	}
+	refresh := config.GlobalConfig.TokenRefreshTime
+	if refresh <= 0 {
+		refresh = config.TOKEN_REFRESH_TIME
+	}
+	return token_bucket.NewTokenBucket(refresh, tokens)
+}
+
+// Tezine iz konfiguracije, vrednost <= 0 znaci podrazumevanu tezinu
+func weight(value int, defaultValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// Tokeni za procitane ili upisane bajtove, jedan token na svakih BytesPerToken bajtova
+func bytesCost(bytes int) int {
+	return bytes / weight(config.GlobalConfig.BytesPerToken, config.BYTES_PER_TOKEN)
+}
+
+func readCost() int {
+	return weight(config.GlobalConfig.ReadWeight, config.READ_WEIGHT)
+}
+
+func writeCost(key string, value []byte) int {
+	return weight(config.GlobalConfig.WriteWeight, config.WRITE_WEIGHT) + bytesCost(len(key)+len(value))
+}
+
+func scanCost(pageSize int) int {
+	return weight(config.GlobalConfig.ScanWeight, config.SCAN_WEIGHT) * pageSize
+}
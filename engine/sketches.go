@@ -0,0 +1,356 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"projekat_nasp/bloom_filter"
+	"projekat_nasp/config"
+	"projekat_nasp/countMinSketch"
+	"projekat_nasp/hyperloglog"
+	"projekat_nasp/memTable"
+	"projekat_nasp/simhash"
+	"strconv"
+	"strings"
+)
+
+/*
+Imenovane verovatnosne strukture se cuvaju kao interni kljucevi SKETCH_PREFIX + tip + "/" + ime,
+pa se kao i svaki drugi upis beleze u WAL i flush-uju u SSTabele, a korisnicki Get i skeniranja
+ih ne vide. Svaka komanda ucita strukturu iz baze, izmeni je i upise nazad.
+*/
+const (
+	SKETCH_PREFIX     = memTable.INTERNAL_KEY_PREFIX + "sketch/"
+	BLOOM_FILTER_TYPE = "bf"
+	CMS_TYPE          = "cms"
+	HLL_TYPE          = "hll"
+	SIMHASH_TYPE      = "simhash"
+)
+
+var (
+	ErrSketchNotFound = errors.New("engine: sketch does not exist")
+	ErrSketchExists   = errors.New("engine: sketch already exists")
+)
+
+func sketchKey(sketchType string, name string) string {
+	return SKETCH_PREFIX + sketchType + "/" + name
+}
+
+func (engine *Engine) loadSketch(sketchType string, name string) ([]byte, error) {
+	stored, found := engine.get(sketchKey(sketchType, name))
+	if !found {
+		return nil, ErrSketchNotFound
+	}
+	return stored, nil
+}
+
+func (engine *Engine) sketchExists(sketchType string, name string) bool {
+	_, found := engine.get(sketchKey(sketchType, name))
+	return found
+}
+
+// BF.CREATE, pravi Bloom filter za expectedElements elemenata sa datom verovatnocom lazno pozitivnog odgovora
+func (engine *Engine) BloomCreate(name string, expectedElements int, falsePositiveRate float64) error {
+	if engine.sketchExists(BLOOM_FILTER_TYPE, name) {
+		return ErrSketchExists
+	}
+	if expectedElements <= 0 || falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return errors.New("engine: invalid bloom filter parameters")
+	}
+	filter := bloom_filter.NewBloomFilterUnique(expectedElements, falsePositiveRate)
+	engine.put(sketchKey(BLOOM_FILTER_TYPE, name), filter.Save())
+	return nil
+}
+
+// BF.ADD, filter koji ne postoji se pravi sa parametrima iz konfiguracije
+func (engine *Engine) BloomAdd(name string, item string) error {
+	var filter *bloom_filter.BloomFilterUnique
+	stored, err := engine.loadSketch(BLOOM_FILTER_TYPE, name)
+	if err == ErrSketchNotFound {
+		expected := config.GlobalConfig.BloomExpectedElements
+		if expected <= 0 {
+			expected = config.EXPECTED_EL
+		}
+		rate := config.GlobalConfig.BloomFalsePositiveRate
+		if rate <= 0 {
+			rate = config.FALSE_POSITIVE_RATE
+		}
+		filter = bloom_filter.NewBloomFilterUnique(expected, rate)
+	} else if err != nil {
+		return err
+	} else if filter, err = bloom_filter.Decode(stored); err != nil {
+		return err
+	}
+	filter.Add([]byte(item))
+	engine.put(sketchKey(BLOOM_FILTER_TYPE, name), filter.Save())
+	return nil
+}
+
+// BF.EXISTS, false znaci da element sigurno nije dodat
+func (engine *Engine) BloomExists(name string, item string) (bool, error) {
+	stored, err := engine.loadSketch(BLOOM_FILTER_TYPE, name)
+	if err != nil {
+		return false, err
+	}
+	filter, err := bloom_filter.Decode(stored)
+	if err != nil {
+		return false, err
+	}
+	return filter.Read([]byte(item)), nil
+}
+
+// CMS.CREATE, epsilon je dozvoljena greska procene, a delta verovatnoca da greska bude veca
+func (engine *Engine) CmsCreate(name string, epsilon float64, delta float64) error {
+	if engine.sketchExists(CMS_TYPE, name) {
+		return ErrSketchExists
+	}
+	if epsilon <= 0 || epsilon >= 1 || delta <= 0 || delta >= 1 {
+		return errors.New("engine: invalid count-min sketch parameters")
+	}
+	sketch := countMinSketch.NewCountMinSketch(epsilon, 1-delta)
+	engine.put(sketchKey(CMS_TYPE, name), sketch.Serialize())
+	return nil
+}
+
+func (engine *Engine) loadCms(name string) (*countMinSketch.CountMinSketch, error) {
+	stored, err := engine.loadSketch(CMS_TYPE, name)
+	if err != nil {
+		return nil, err
+	}
+	return countMinSketch.Deserialize(stored)
+}
+
+// CMS.INCR, povecava ucestalost elementa za count i vraca novu procenu
+func (engine *Engine) CmsIncr(name string, item string, count int) (uint, error) {
+	sketch, err := engine.loadCms(name)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < count; i++ {
+		sketch.AddKey(item)
+	}
+	engine.put(sketchKey(CMS_TYPE, name), sketch.Serialize())
+	return sketch.FindKeyFrequency(item), nil
+}
+
+// CMS.QUERY, procena ucestalosti elementa, nikad manja od stvarne
+func (engine *Engine) CmsQuery(name string, item string) (uint, error) {
+	sketch, err := engine.loadCms(name)
+	if err != nil {
+		return 0, err
+	}
+	return sketch.FindKeyFrequency(item), nil
+}
+
+func (engine *Engine) loadHll(name string) (*hyperloglog.HLLPlus, error) {
+	stored, err := engine.loadSketch(HLL_TYPE, name)
+	if err != nil {
+		return nil, err
+	}
+	return hyperloglog.DeserializePlus(stored)
+}
+
+func newHll() *hyperloglog.HLLPlus {
+	return hyperloglog.NewHLLPlus(uint8(config.GlobalConfig.HllPlusPrecision))
+}
+
+// HLL.ADD, HyperLogLog++ koji ne postoji se pravi sa preciznoscu iz konfiguracije
+func (engine *Engine) HllAdd(name string, items ...string) error {
+	hll, err := engine.loadHll(name)
+	if err == ErrSketchNotFound {
+		hll = newHll()
+	} else if err != nil {
+		return err
+	}
+	for _, item := range items {
+		hll.Add(item)
+	}
+	engine.put(sketchKey(HLL_TYPE, name), hll.Serialize())
+	return nil
+}
+
+// Ucitava HyperLogLog-ove, onaj koji ne postoji se racuna kao prazan
+func (engine *Engine) loadHlls(names []string) ([]*hyperloglog.HLLPlus, error) {
+	sketches := make([]*hyperloglog.HLLPlus, len(names))
+	for i, name := range names {
+		hll, err := engine.loadHll(name)
+		if err == ErrSketchNotFound {
+			hll = newHll()
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, name)
+		}
+		sketches[i] = hll
+	}
+	return sketches, nil
+}
+
+// HLL.COUNT, procena broja razlicitih elemenata, za vise imena procena unije
+func (engine *Engine) HllCount(names ...string) (float64, error) {
+	sketches, err := engine.loadHlls(names)
+	if err != nil {
+		return 0, err
+	}
+	return hyperloglog.UnionCount(sketches...)
+}
+
+// HLL.INTERSECT, procena broja elemenata koji su dodati u oba HyperLogLog-a
+func (engine *Engine) HllIntersect(first string, second string) (float64, error) {
+	sketches, err := engine.loadHlls([]string{first, second})
+	if err != nil {
+		return 0, err
+	}
+	return hyperloglog.IntersectionCount(sketches[0], sketches[1])
+}
+
+// HLL.MERGE, u destination upisuje uniju destination i svih sources
+func (engine *Engine) HllMerge(destination string, sources ...string) error {
+	merged, err := engine.loadHll(destination)
+	if err == ErrSketchNotFound {
+		merged = newHll()
+	} else if err != nil {
+		return err
+	}
+	for _, name := range sources {
+		hll, err := engine.loadHll(name)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, name)
+		}
+		if err = merged.Merge(hll); err != nil {
+			return err
+		}
+	}
+	engine.put(sketchKey(HLL_TYPE, destination), merged.Serialize())
+	return nil
+}
+
+// SIMHASH.ADD, pamti otisak teksta pod imenom name, postojeci otisak se zamenjuje
+func (engine *Engine) SimHashAdd(name string, text string) error {
+	data, err := simhash.NewSimHashWithOptions(text, simHashOptions()).SerializeSH()
+	if err != nil {
+		return err
+	}
+	engine.put(sketchKey(SIMHASH_TYPE, name), data)
+	return nil
+}
+
+func (engine *Engine) loadSimHash(name string) (*simhash.SimHash, error) {
+	stored, err := engine.loadSketch(SIMHASH_TYPE, name)
+	if err != nil {
+		return nil, err
+	}
+	return simhash.DeserializeSH(stored)
+}
+
+// SIMHASH.DISTANCE, Hamming rastojanje otisaka dva sacuvana teksta
+func (engine *Engine) SimHashDistance(first string, second string) (int, error) {
+	a, err := engine.loadSimHash(first)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", err, first)
+	}
+	b, err := engine.loadSimHash(second)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", err, second)
+	}
+	return simhash.HammingDistance(a, b), nil
+}
+
+func ok(err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return "OK", nil
+}
+
+// Da li komanda menja strukturu, takve se klijentu naplacuju iz budzeta za upise
+func IsWriteCommand(command string) bool {
+	switch strings.ToUpper(command) {
+	case "BF.CREATE", "BF.ADD", "CMS.CREATE", "CMS.INCR", "HLL.ADD", "HLL.MERGE", "SIMHASH.ADD":
+		return true
+	}
+	return false
+}
+
+/*
+Izvrsava komandu nad imenovanom strukturom i vraca odgovor kao tekst:
+
+	BF.CREATE ime ocekivaniBroj verovatnoca   BF.ADD ime element     BF.EXISTS ime element
+	CMS.CREATE ime epsilon delta              CMS.INCR ime element [broj]   CMS.QUERY ime element
+	HLL.ADD ime element...                    HLL.COUNT ime...       HLL.MERGE odrediste izvor...
+	HLL.INTERSECT ime1 ime2
+	SIMHASH.ADD ime tekst...                  SIMHASH.DISTANCE ime1 ime2
+*/
+func (engine *Engine) Execute(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errors.New("engine: empty command")
+	}
+	command := strings.ToUpper(args[0])
+	args = args[1:]
+	arity := map[string]int{
+		"BF.CREATE": 3, "BF.ADD": 2, "BF.EXISTS": 2,
+		"CMS.CREATE": 3, "CMS.INCR": 2, "CMS.QUERY": 2,
+		"HLL.ADD": 2, "HLL.COUNT": 1, "HLL.MERGE": 2, "HLL.INTERSECT": 2,
+		"SIMHASH.ADD": 2, "SIMHASH.DISTANCE": 2,
+	}
+	minArgs, known := arity[command]
+	if !known {
+		return "", errors.New("engine: unknown command " + command)
+	}
+	if len(args) < minArgs {
+		return "", fmt.Errorf("engine: %s needs at least %d arguments", command, minArgs)
+	}
+
+	switch command {
+	case "BF.CREATE":
+		expected, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "", err
+		}
+		rate, err := strconv.ParseFloat(args[2], 64)
+		if err != nil {
+			return "", err
+		}
+		return ok(engine.BloomCreate(args[0], expected, rate))
+	case "BF.ADD":
+		return ok(engine.BloomAdd(args[0], args[1]))
+	case "BF.EXISTS":
+		exists, err := engine.BloomExists(args[0], args[1])
+		return strconv.FormatBool(exists), err
+	case "CMS.CREATE":
+		epsilon, err := strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			return "", err
+		}
+		delta, err := strconv.ParseFloat(args[2], 64)
+		if err != nil {
+			return "", err
+		}
+		return ok(engine.CmsCreate(args[0], epsilon, delta))
+	case "CMS.INCR":
+		count := 1
+		if len(args) > 2 {
+			var err error
+			if count, err = strconv.Atoi(args[2]); err != nil || count < 0 {
+				return "", errors.New("engine: invalid count " + args[2])
+			}
+		}
+		frequency, err := engine.CmsIncr(args[0], args[1], count)
+		return strconv.FormatUint(uint64(frequency), 10), err
+	case "CMS.QUERY":
+		frequency, err := engine.CmsQuery(args[0], args[1])
+		return strconv.FormatUint(uint64(frequency), 10), err
+	case "HLL.ADD":
+		return ok(engine.HllAdd(args[0], args[1:]...))
+	case "HLL.COUNT":
+		count, err := engine.HllCount(args...)
+		return strconv.FormatFloat(count, 'f', 0, 64), err
+	case "HLL.INTERSECT":
+		count, err := engine.HllIntersect(args[0], args[1])
+		return strconv.FormatFloat(count, 'f', 0, 64), err
+	case "HLL.MERGE":
+		return ok(engine.HllMerge(args[0], args[1:]...))
+	case "SIMHASH.ADD":
+		return ok(engine.SimHashAdd(args[0], strings.Join(args[1:], " ")))
+	default: // SIMHASH.DISTANCE
+		distance, err := engine.SimHashDistance(args[0], args[1])
+		return strconv.Itoa(distance), err
+	}
+}
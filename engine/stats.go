@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"projekat_nasp/config"
+	"projekat_nasp/ddsketch"
+	"projekat_nasp/memTable"
+	"time"
+)
+
+// Skice kvantila se cuvaju pod internim kljucevima, kao i stanje pracenja ucestalosti kljuceva
+const (
+	VALUE_SIZE_SKETCH_KEY  = memTable.INTERNAL_KEY_PREFIX + "stats/value_size"
+	GET_LATENCY_SKETCH_KEY = memTable.INTERNAL_KEY_PREFIX + "stats/get_latency"
+	PUT_LATENCY_SKETCH_KEY = memTable.INTERNAL_KEY_PREFIX + "stats/put_latency"
+)
+
+// Broj vrednosti i procene medijane, 95. i 99. percentila
+type Quantiles struct {
+	Count uint64
+	P50   float64
+	P95   float64
+	P99   float64
+}
+
+/*
+Raspodele koje engine prati DDSketch-om: velicine vrednosti u bajtovima (svaki Put) i trajanja
+Get i Put operacija u mikrosekundama. Procene su tacne do DDSketchAccuracy relativno.
+*/
+type Stats struct {
+	ValueSize  Quantiles
+	GetLatency Quantiles
+	PutLatency Quantiles
+}
+
+func quantiles(sketch *ddsketch.DDSketch) Quantiles {
+	return Quantiles{
+		Count: sketch.Count(),
+		P50:   sketch.Quantile(0.5),
+		P95:   sketch.Quantile(0.95),
+		P99:   sketch.Quantile(0.99),
+	}
+}
+
+func (engine *Engine) Stats() Stats {
+	return Stats{
+		ValueSize:  quantiles(engine.valueSizes),
+		GetLatency: quantiles(engine.getLatency),
+		PutLatency: quantiles(engine.putLatency),
+	}
+}
+
+func microseconds(start time.Time) float64 {
+	return float64(time.Since(start).Nanoseconds()) / 1e3
+}
+
+func newQuantileSketch() *ddsketch.DDSketch {
+	return ddsketch.NewDDSketch(config.GlobalConfig.DDSketchAccuracy)
+}
+
+// Ucitava skicu sacuvanu pod key, ili pravi praznu
+func (engine *Engine) loadQuantileSketch(key string) *ddsketch.DDSketch {
+	if stored, found := engine.get(key); found {
+		if sketch, err := ddsketch.Deserialize(stored); err == nil {
+			return sketch
+		}
+	}
+	return newQuantileSketch()
+}
+
+func (engine *Engine) loadStats() {
+	engine.valueSizes = engine.loadQuantileSketch(VALUE_SIZE_SKETCH_KEY)
+	engine.getLatency = engine.loadQuantileSketch(GET_LATENCY_SKETCH_KEY)
+	engine.putLatency = engine.loadQuantileSketch(PUT_LATENCY_SKETCH_KEY)
+}
+
+// Upisuje skice u bazu kao interne kljuceve
+func (engine *Engine) saveStats() {
+	engine.put(VALUE_SIZE_SKETCH_KEY, engine.valueSizes.Serialize())
+	engine.put(GET_LATENCY_SKETCH_KEY, engine.getLatency.Serialize())
+	engine.put(PUT_LATENCY_SKETCH_KEY, engine.putLatency.Serialize())
+}
@@ -3,6 +3,7 @@ package hyperloglog
 import (
 	"bytes"
 	"encoding/gob"
+	"errors"
 	"hash"
 	"hash/fnv"
 	"math"
@@ -115,6 +116,39 @@ func UcitajHLL(putanja string) HLL {
 	return *hll
 }
 
+// Dodaje u hll sve elemente iz other, oba moraju imati istu preciznost i duzinu hesa
+func (hll *HLL) Merge(other *HLL) error {
+	if hll.p != other.p || hll.b64 != other.b64 {
+		return errors.New("hyperloglog: cannot merge sketches with different precision or hash size")
+	}
+	for i, val := range other.set {
+		if val > hll.set[i] {
+			hll.set[i] = val
+		}
+	}
+	return nil
+}
+
+// HLL u bajtovima, za cuvanje u bazi
+func (hll *HLL) Serialize() []byte {
+	data, _ := hll.GobEncode()
+	return data
+}
+
+func Deserialize(data []byte) (HLL, error) {
+	var hll HLL
+	err := hll.GobDecode(data)
+	if err != nil {
+		return hll, err
+	}
+	if hll.p < 4 || hll.p > 16 || uint64(len(hll.set)) != hll.m || hll.m != 1<<hll.p {
+		return hll, errors.New("hyperloglog: invalid serialized sketch")
+	}
+	hll.hasher32 = fnv.New32a()
+	hll.hasher64 = fnv.New64a()
+	return hll, nil
+}
+
 func (hll HLL) GobEncode() ([]byte, error) {
 	var b bytes.Buffer
 	enc := gob.NewEncoder(&b)
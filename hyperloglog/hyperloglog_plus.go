@@ -0,0 +1,331 @@
+package hyperloglog
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"projekat_nasp/config"
+	"sort"
+)
+
+const (
+	PLUS_MIN_PRECISION = 4
+	PLUS_MAX_PRECISION = 18
+	SPARSE_PRECISION   = 25 // preciznost indeksa u retkoj reprezentaciji
+	PLUS_VERSION       = 1
+)
+
+/*
+HyperLogLog++: 64-bitni hes, pa nema korekcije za velike kardinalnosti, i dve reprezentacije.
+Dok je elemenata malo HLL je redak (sparse): pamte se samo zauzeti registri, i to sa
+preciznoscu SPARSE_PRECISION, pa je procena za male skupove skoro tacna. Kada retka lista
+postane veca od gustog niza registara, prelazi se na gustu (dense) reprezentaciju sa 2^p
+registara. Procena koristi Ertl-ov poboljsani estimator, koji ispravlja pristrasnost
+originalne formule na celom opsegu bez empirijskih tabela.
+*/
+type HLLPlus struct {
+	p         uint8
+	sparse    map[uint32]uint8 // indeks sa SPARSE_PRECISION bita -> najveci broj nula, nil kada je gust
+	registers []uint8
+}
+
+// Preciznost van opsega PLUS_MIN_PRECISION..PLUS_MAX_PRECISION se zamenjuje podrazumevanom
+func NewHLLPlus(p uint8) *HLLPlus {
+	if p < PLUS_MIN_PRECISION || p > PLUS_MAX_PRECISION {
+		p = config.HLL_PLUS_PRECISION
+	}
+	return &HLLPlus{p: p, sparse: make(map[uint32]uint8)}
+}
+
+func (hll *HLLPlus) Precision() uint8 {
+	return hll.p
+}
+
+func (hll *HLLPlus) IsSparse() bool {
+	return hll.sparse != nil
+}
+
+// 64-bitni FNV-1a, pa finalizer iz MurmurHash3 da bi i visi bitovi bili dobro izmesani
+func hash64(key string) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(key))
+	x := hasher.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+func (hll *HLLPlus) Add(key string) {
+	hll.AddHash(hash64(key))
+}
+
+// Dodaje vec izracunat 64-bitni hes
+func (hll *HLLPlus) AddHash(x uint64) {
+	if hll.sparse != nil {
+		index := uint32(x >> (64 - SPARSE_PRECISION))
+		rho := uint8(bits.LeadingZeros64(x<<SPARSE_PRECISION|1<<(SPARSE_PRECISION-1))) + 1
+		if rho > hll.sparse[index] {
+			hll.sparse[index] = rho
+			if len(hll.sparse) > hll.sparseLimit() {
+				hll.toDense()
+			}
+		}
+		return
+	}
+	index := x >> (64 - hll.p)
+	rho := uint8(bits.LeadingZeros64(x<<hll.p|1<<(hll.p-1))) + 1
+	if rho > hll.registers[index] {
+		hll.registers[index] = rho
+	}
+}
+
+// Retka lista zauzima oko 4 bajta po elementu, a gust niz 6 bita po registru
+func (hll *HLLPlus) sparseLimit() int {
+	return (1 << hll.p) * 6 / 32
+}
+
+// Registar guste reprezentacije za element retke: visi bitovi indeksa daju registar, a nize
+// bitove indeksa treba dodati na pocetak niza u kome se broje nule
+func (hll *HLLPlus) denseRegister(index uint32, rho uint8) (uint32, uint8) {
+	shift := SPARSE_PRECISION - hll.p
+	rest := index & (1<<shift - 1)
+	if rest != 0 {
+		return index >> shift, uint8(bits.LeadingZeros32(rest)-(32-int(shift))) + 1
+	}
+	return index >> shift, rho + shift
+}
+
+func (hll *HLLPlus) toDense() {
+	hll.registers = make([]uint8, 1<<hll.p)
+	for index, rho := range hll.sparse {
+		register, value := hll.denseRegister(index, rho)
+		if value > hll.registers[register] {
+			hll.registers[register] = value
+		}
+	}
+	hll.sparse = nil
+}
+
+// Procena broja razlicitih elemenata
+func (hll *HLLPlus) Count() float64 {
+	if hll.sparse != nil {
+		// linear counting nad 2^SPARSE_PRECISION registara
+		m := float64(uint64(1) << SPARSE_PRECISION)
+		return m * math.Log(m/(m-float64(len(hll.sparse))))
+	}
+	q := 64 - int(hll.p)
+	histogram := make([]int, q+2)
+	for _, value := range hll.registers {
+		histogram[value]++
+	}
+	m := float64(len(hll.registers))
+	z := m * tau(1-float64(histogram[q+1])/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + float64(histogram[k]))
+	}
+	z += m * sigma(float64(histogram[0])/m)
+	return m * m / (2 * math.Ln2 * z)
+}
+
+func sigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		previous := z
+		z += x * y
+		y += y
+		if z == previous {
+			return z
+		}
+	}
+}
+
+func tau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		previous := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == previous {
+			return z / 3
+		}
+	}
+}
+
+// Dodaje u hll sve elemente iz other, preciznosti moraju biti iste
+func (hll *HLLPlus) Merge(other *HLLPlus) error {
+	if hll.p != other.p {
+		return errors.New("hyperloglog: cannot merge sketches with different precision")
+	}
+	if hll.sparse != nil && other.sparse != nil {
+		for index, rho := range other.sparse {
+			if rho > hll.sparse[index] {
+				hll.sparse[index] = rho
+			}
+		}
+		if len(hll.sparse) > hll.sparseLimit() {
+			hll.toDense()
+		}
+		return nil
+	}
+	if hll.sparse != nil {
+		hll.toDense()
+	}
+	if other.sparse != nil {
+		for index, rho := range other.sparse {
+			register, value := hll.denseRegister(index, rho)
+			if value > hll.registers[register] {
+				hll.registers[register] = value
+			}
+		}
+		return nil
+	}
+	for i, value := range other.registers {
+		if value > hll.registers[i] {
+			hll.registers[i] = value
+		}
+	}
+	return nil
+}
+
+func (hll *HLLPlus) Clone() *HLLPlus {
+	clone := &HLLPlus{p: hll.p}
+	if hll.sparse != nil {
+		clone.sparse = make(map[uint32]uint8, len(hll.sparse))
+		for index, rho := range hll.sparse {
+			clone.sparse[index] = rho
+		}
+	} else {
+		clone.registers = append([]uint8{}, hll.registers...)
+	}
+	return clone
+}
+
+// Unija vise HLL-ova (npr. dnevnih u nedeljni), ulazni HLL-ovi se ne menjaju
+func Union(sketches ...*HLLPlus) (*HLLPlus, error) {
+	if len(sketches) == 0 {
+		return nil, errors.New("hyperloglog: union of no sketches")
+	}
+	union := sketches[0].Clone()
+	for _, sketch := range sketches[1:] {
+		if err := union.Merge(sketch); err != nil {
+			return nil, err
+		}
+	}
+	return union, nil
+}
+
+// Procena broja razlicitih elemenata u bar jednom od HLL-ova
+func UnionCount(sketches ...*HLLPlus) (float64, error) {
+	union, err := Union(sketches...)
+	if err != nil {
+		return 0, err
+	}
+	return union.Count(), nil
+}
+
+// Procena preseka dva skupa po principu ukljucenja-iskljucenja, |A| + |B| - |A u B|.
+// Greska je reda greske unije, pa je procena neprecizna kada je presek mali u odnosu na skupove.
+func IntersectionCount(a *HLLPlus, b *HLLPlus) (float64, error) {
+	union, err := UnionCount(a, b)
+	if err != nil {
+		return 0, err
+	}
+	return math.Max(0, a.Count()+b.Count()-union), nil
+}
+
+/*
+Binarni format:
+
+	verzija | p | 0 = redak, 1 = gust
+	redak: broj elemenata (uvarint), pa sortirani elementi (indeks << 6 | rho) kao razlike (uvarint)
+	gust: 2^p registara po 6 bita
+*/
+func (hll *HLLPlus) Serialize() []byte {
+	data := []byte{PLUS_VERSION, hll.p, 0}
+	if hll.sparse != nil {
+		entries := make([]uint32, 0, len(hll.sparse))
+		for index, rho := range hll.sparse {
+			entries = append(entries, index<<6|uint32(rho))
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i] < entries[j] })
+		data = binary.AppendUvarint(data, uint64(len(entries)))
+		previous := uint32(0)
+		for _, entry := range entries {
+			data = binary.AppendUvarint(data, uint64(entry-previous))
+			previous = entry
+		}
+		return data
+	}
+	data[2] = 1
+	packed := make([]byte, (len(hll.registers)*6+7)/8)
+	for i, value := range hll.registers {
+		bit := i * 6
+		word := uint16(value&0x3f) << (bit % 8)
+		packed[bit/8] |= byte(word)
+		if bit/8+1 < len(packed) {
+			packed[bit/8+1] |= byte(word >> 8)
+		}
+	}
+	return append(data, packed...)
+}
+
+func DeserializePlus(data []byte) (*HLLPlus, error) {
+	invalid := errors.New("hyperloglog: invalid serialized sketch")
+	if len(data) < 3 || data[0] != PLUS_VERSION || data[1] < PLUS_MIN_PRECISION || data[1] > PLUS_MAX_PRECISION {
+		return nil, invalid
+	}
+	hll := &HLLPlus{p: data[1]}
+	dense := data[2] == 1
+	data = data[3:]
+
+	if dense {
+		m := 1 << hll.p
+		if len(data) != (m*6+7)/8 {
+			return nil, invalid
+		}
+		hll.registers = make([]uint8, m)
+		for i := range hll.registers {
+			bit := i * 6
+			word := uint16(data[bit/8])
+			if bit/8+1 < len(data) {
+				word |= uint16(data[bit/8+1]) << 8
+			}
+			hll.registers[i] = uint8(word>>(bit%8)) & 0x3f
+		}
+		return hll, nil
+	}
+
+	count, n := binary.Uvarint(data)
+	if n <= 0 || count > uint64(len(data)) {
+		return nil, invalid
+	}
+	data = data[n:]
+	hll.sparse = make(map[uint32]uint8, count)
+	entry := uint64(0)
+	for i := uint64(0); i < count; i++ {
+		delta, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, invalid
+		}
+		data = data[n:]
+		entry += delta
+		if entry >= 1<<(SPARSE_PRECISION+6) {
+			return nil, invalid
+		}
+		hll.sparse[uint32(entry>>6)] = uint8(entry & 0x3f)
+	}
+	return hll, nil
+}
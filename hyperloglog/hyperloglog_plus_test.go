@@ -0,0 +1,88 @@
+package hyperloglog
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func addKeys(hll *HLLPlus, from, to int) {
+	for i := from; i < to; i++ {
+		hll.Add(fmt.Sprint("key", i))
+	}
+}
+
+// Redak HLL preveden u gust mora imati iste registre kao HLL koji je od pocetka gust
+func TestSparseToDenseMatchesDense(t *testing.T) {
+	sparse := NewHLLPlus(10)
+	dense := NewHLLPlus(10)
+	dense.toDense()
+	addKeys(sparse, 0, 150)
+	addKeys(dense, 0, 150)
+	if !sparse.IsSparse() {
+		t.Fatal("150 keys should still fit in the sparse representation")
+	}
+
+	sparse.toDense()
+	for i := range dense.registers {
+		if sparse.registers[i] != dense.registers[i] {
+			t.Fatalf("register %d = %d, want %d", i, sparse.registers[i], dense.registers[i])
+		}
+	}
+}
+
+func TestSwitchesToDense(t *testing.T) {
+	hll := NewHLLPlus(10)
+	addKeys(hll, 0, 1000)
+	if hll.IsSparse() {
+		t.Fatal("sketch stayed sparse past its limit")
+	}
+	if count := hll.Count(); math.Abs(count-1000) > 100 {
+		t.Errorf("count = %.0f, want about 1000", count)
+	}
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 50, 5000} {
+		hll := NewHLLPlus(10)
+		addKeys(hll, 0, n)
+		restored, err := DeserializePlus(hll.Serialize())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.IsSparse() != hll.IsSparse() || restored.Count() != hll.Count() {
+			t.Errorf("%d keys: restored count %.2f (sparse %v), want %.2f (sparse %v)",
+				n, restored.Count(), restored.IsSparse(), hll.Count(), hll.IsSparse())
+		}
+	}
+}
+
+func TestDeserializeRejectsTruncated(t *testing.T) {
+	hll := NewHLLPlus(10)
+	addKeys(hll, 0, 5000)
+	data := hll.Serialize()
+	if _, err := DeserializePlus(data[:len(data)-1]); err == nil {
+		t.Fatal("truncated sketch was accepted")
+	}
+}
+
+// Spajanje retkog i gustog HLL-a daje isto sto i dodavanje svih kljuceva u jedan
+func TestMergeSparseIntoDense(t *testing.T) {
+	dense := NewHLLPlus(10)
+	addKeys(dense, 0, 2000)
+	sparse := NewHLLPlus(10)
+	addKeys(sparse, 1900, 2100)
+	all := NewHLLPlus(10)
+	addKeys(all, 0, 2100)
+
+	err := dense.Merge(sparse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dense.Count() != all.Count() {
+		t.Errorf("merged count = %.2f, want %.2f", dense.Count(), all.Count())
+	}
+	if err := dense.Merge(NewHLLPlus(12)); err == nil {
+		t.Error("sketches with different precision were merged")
+	}
+}
@@ -0,0 +1,85 @@
+package lsm_tree
+
+import (
+	"projekat_nasp/config"
+	"projekat_nasp/sstable"
+)
+
+/*
+Jedan korak kompakcije koji je izabrao CompactionPicker. Ulazne tabele se spajaju
+u nove tabele na nivou OutputLevel, osim ako je postavljeno:
+  - Move: tabele se samo preimenuju tako da pripadaju nivou OutputLevel
+  - DeleteOnly: tabele se brisu bez prepisivanja (FIFO)
+*/
+type Compaction struct {
+	Inputs         []sstable.TableInfo
+	OutputLevel    int
+	TargetFileSize uint64
+	Move           bool
+	DeleteOnly     bool
+}
+
+// Strategija kompakcije: na osnovu trenutnih tabela (od najnovije ka najstarijoj) bira
+// sledeci korak, ili vraca false kada kompakcija nije potrebna
+type CompactionPicker interface {
+	Pick(tables []sstable.TableInfo) (Compaction, bool)
+}
+
+// Picker za algoritam zadat imenom iz config.CompactionAlgorithm. Nepoznato ime znaci leveled.
+func NewCompactionPicker(algorithm string) CompactionPicker {
+	switch algorithm {
+	case "sizeTiered":
+		return &SizeTieredPicker{}
+	case "universal":
+		return &UniversalPicker{}
+	case "fifo":
+		return &FIFOPicker{}
+	default:
+		return NewLeveledPicker()
+	}
+}
+
+// Kompakcija algoritmom iz konfiguracije
+func Compact() error {
+	if config.GlobalConfig.CompactionAlgorithm == "sizeTiered" {
+		return SizeTiered()
+	}
+	return RunCompaction(NewCompactionPicker(config.GlobalConfig.CompactionAlgorithm))
+}
+
+// Izvrsava korake koje bira picker dok god ih ima
+func RunCompaction(picker CompactionPicker) error {
+	for {
+		compaction, ok := picker.Pick(sstable.ListTables())
+		if !ok {
+			return nil
+		}
+		err := compaction.run()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (compaction Compaction) run() error {
+	switch {
+	case compaction.DeleteOnly:
+		for _, table := range compaction.Inputs {
+			err := removeTable(table.Path)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case compaction.Move:
+		for _, table := range compaction.Inputs {
+			err := sstable.MoveTable(table, compaction.OutputLevel)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return MergeTables(compaction.Inputs, compaction.OutputLevel, compaction.TargetFileSize)
+	}
+}
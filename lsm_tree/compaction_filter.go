@@ -0,0 +1,63 @@
+package lsm_tree
+
+import (
+	"projekat_nasp/memTable"
+	"projekat_nasp/sstable"
+	"sync"
+)
+
+// Odluka compaction filtera za jedan zapis
+type FilterDecision int
+
+const (
+	FILTER_KEEP    FilterDecision = iota // zapis ostaje nepromenjen
+	FILTER_DROP                          // zapis se brise
+	FILTER_REWRITE                       // zapis ostaje sa novom vrednoscu
+)
+
+/*
+Korisnicka funkcija koja se poziva za svaki zapis koji prezivi spajanje tabela (samo za
+najnoviju verziju kljuca, tombstone-ovi i operandi Merge operacije se ne prosledjuju). level
+je nivo na koji se zapis upisuje. Uz FILTER_REWRITE vraca se nova vrednost, a timestamp zapisa ostaje isti.
+
+Primeri: brisanje svih kljuceva obrisanog tenanta po prefiksu, ili postepeno prevodjenje
+vrednosti u novi format bez posebnog prolaska kroz celu bazu.
+*/
+type CompactionFilter func(level int, key string, value []byte, timestamp uint64) (FilterDecision, []byte)
+
+var (
+	compactionFilter      CompactionFilter
+	compactionFilterMutex sync.Mutex
+)
+
+// Postavlja filter koji koriste sve naredne kompakcije, nil ga iskljucuje
+func SetCompactionFilter(filter CompactionFilter) {
+	compactionFilterMutex.Lock()
+	defer compactionFilterMutex.Unlock()
+	compactionFilter = filter
+}
+
+func currentCompactionFilter() CompactionFilter {
+	compactionFilterMutex.Lock()
+	defer compactionFilterMutex.Unlock()
+	return compactionFilter
+}
+
+/*
+Primenjuje filter na zapis. Vraca false ako zapis treba izostaviti iz izlaza. Obrisan kljuc
+koji mozda postoji u nekoj tabeli ispod izlaza ne sme samo da nestane, jer bi tada stara
+vrednost ponovo postala vidljiva, pa se umesto njega upisuje tombstone.
+*/
+func applyCompactionFilter(filter CompactionFilter, level int, r *memTable.MemTableEntry, below []sstable.TableInfo) (bool, FilterDecision) {
+	decision, value := filter(level, r.GetKey(), r.GetValue(), r.GetTimeStamp())
+	switch decision {
+	case FILTER_DROP:
+		if !mayExistBelow(below, r.GetKey()) {
+			return false, decision
+		}
+		*r = memTable.NewMemTableEntry(r.GetKey(), nil, 1, r.GetTimeStamp())
+	case FILTER_REWRITE:
+		*r = memTable.NewMemTableEntry(r.GetKey(), value, 0, r.GetTimeStamp())
+	}
+	return true, decision
+}
@@ -0,0 +1,37 @@
+package lsm_tree
+
+import (
+	"projekat_nasp/config"
+	"projekat_nasp/sstable"
+)
+
+/*
+FIFO kompakcija, za podatke koji vremenom zastarevaju (npr. vremenske serije).
+Tabele se nikada ne spajaju, vec se, kada njihova ukupna velicina predje FifoMaxTotalSize,
+brisu najstarije dok ukupna velicina ne padne ispod granice.
+*/
+type FIFOPicker struct{}
+
+func fifoMaxTotalSize() uint64 {
+	maxSize := config.GlobalConfig.FifoMaxTotalSize
+	if maxSize <= 0 {
+		maxSize = config.FIFO_MAX_TOTAL_SIZE
+	}
+	return uint64(maxSize)
+}
+
+func (picker *FIFOPicker) Pick(tables []sstable.TableInfo) (Compaction, bool) {
+	total := tablesBytes(tables)
+	maxSize := fifoMaxTotalSize()
+	if total <= maxSize {
+		return Compaction{}, false
+	}
+
+	// tabele su od najnovije ka najstarijoj, brise se od kraja
+	var oldest []sstable.TableInfo
+	for i := len(tables) - 1; i >= 0 && total > maxSize; i-- {
+		oldest = append(oldest, tables[i])
+		total -= tablesBytes(tables[i : i+1])
+	}
+	return Compaction{Inputs: oldest, DeleteOnly: true}, true
+}
@@ -0,0 +1,197 @@
+package lsm_tree
+
+import (
+	"encoding/gob"
+	"math"
+	"os"
+	"path/filepath"
+	"projekat_nasp/config"
+	"projekat_nasp/sstable"
+)
+
+const CURSORS_PATH = "data/lsm_tree/cursors.gob"
+
+// Najdublji nivo stabla. Nivoi idu od 0 (tabele iz memtabela) do MaxLevels-1.
+func BottomLevel() int {
+	maxLevels := config.GlobalConfig.MaxLevels
+	if maxLevels <= 0 {
+		maxLevels = config.MAX_LEVELS
+	}
+	return maxLevels - 1
+}
+
+// Ciljana velicina nivoa u bajtovima: MaxBytes za nivo 1, a svaki sledeci nivo je ScalingFactor puta veci
+func levelTargetBytes(level int) float64 {
+	maxBytes := config.GlobalConfig.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = config.MAX_BYTES
+	}
+	scalingFactor := config.GlobalConfig.ScalingFactor
+	if scalingFactor <= 0 {
+		scalingFactor = config.SCALING_FACTOR
+	}
+	return float64(maxBytes) * math.Pow(float64(scalingFactor), float64(level-1))
+}
+
+// Broj tabela na nivou 0 posle kog pocinje njihovo spustanje na nivo 1
+func levelZeroTrigger() int {
+	maxTables := config.GlobalConfig.MaxTables
+	if maxTables <= 0 {
+		maxTables = config.MAX_TABLES
+	}
+	return maxTables
+}
+
+func tablesBytes(tables []sstable.TableInfo) uint64 {
+	var total uint64
+	for _, table := range tables {
+		total += table.Properties.DataSize + table.Properties.IndexSize + table.Properties.FilterSize
+	}
+	return total
+}
+
+// Najmanji i najveci kljuc skupa tabela
+func keyRange(tables []sstable.TableInfo) (string, string) {
+	smallest := tables[0].Properties.SmallestKey
+	largest := tables[0].Properties.LargestKey
+	for _, table := range tables[1:] {
+		if table.Properties.SmallestKey < smallest {
+			smallest = table.Properties.SmallestKey
+		}
+		if table.Properties.LargestKey > largest {
+			largest = table.Properties.LargestKey
+		}
+	}
+	return smallest, largest
+}
+
+/*
+Leveled kompakcija. Na nivoima >= 1 tabele se ne preklapaju, pa se u svakom koraku
+sa nivoa koji je najvise preko svoje ciljane velicine spusta samo jedna tabela, zajedno
+sa tabelama sledeceg nivoa koje se sa njom preklapaju. Tabela se bira redom po kljucu:
+za svaki nivo pamti se najveci kljuc poslednje spustene tabele i sledeci put se uzima
+prva tabela iza njega. Sa nivoa 0 se spustaju sve tabele odjednom, jer se preklapaju.
+Koraci se ponavljaju dok svi nivoi ne budu u granicama.
+*/
+type LeveledPicker struct {
+	cursors map[int]string
+}
+
+func NewLeveledPicker() *LeveledPicker {
+	return &LeveledPicker{cursors: loadCursors()}
+}
+
+func LeveledCompaction() error {
+	return RunCompaction(NewLeveledPicker())
+}
+
+// Bira nivo sa najvecim odnosom velicine i cilja (za nivo 0 broja tabela i praga), ako je on >= 1
+func (picker *LeveledPicker) Pick(tables []sstable.TableInfo) (Compaction, bool) {
+	bottom := BottomLevel()
+	_, byLevel := sstable.GroupByLevel(tables)
+
+	bestLevel := -1
+	bestScore := 1.0
+	for lvl := sstable.FLUSH_LEVEL; lvl < bottom; lvl++ {
+		if len(byLevel[lvl]) == 0 {
+			continue
+		}
+		var score float64
+		if lvl == sstable.FLUSH_LEVEL {
+			score = float64(len(byLevel[lvl])) / float64(levelZeroTrigger())
+		} else {
+			score = float64(tablesBytes(byLevel[lvl])) / levelTargetBytes(lvl)
+		}
+		if score >= bestScore {
+			bestLevel = lvl
+			bestScore = score
+		}
+	}
+	if bestLevel == -1 {
+		return Compaction{}, false
+	}
+
+	var inputs []sstable.TableInfo
+	if bestLevel == sstable.FLUSH_LEVEL {
+		inputs = byLevel[bestLevel]
+	} else {
+		inputs = []sstable.TableInfo{pickTable(byLevel[bestLevel], picker.cursors[bestLevel])}
+	}
+	smallest, largest := keyRange(inputs)
+	var overlapping []sstable.TableInfo
+	for _, table := range byLevel[bestLevel+1] {
+		if table.Properties.Overlaps(smallest, largest) {
+			overlapping = append(overlapping, table)
+		}
+	}
+
+	// kursor je samo pomoc pri izboru sledece tabele, pa greska pri cuvanju nije kriticna
+	picker.cursors[bestLevel] = largest
+	saveCursors(picker.cursors)
+
+	compaction := Compaction{OutputLevel: bestLevel + 1, TargetFileSize: SSTABLE_SIZE}
+	// tabela koja se ni sa cim ne preklapa samo se preimenuje, bez ponovnog pisanja. Tabela sa
+	// tombstone-ovima se ipak prepisuje, da bi se izbacili oni kojima ispod nema starije verzije.
+	if _, ok := sstable.NonOverlapping(inputs); ok && len(overlapping) == 0 && !hasTombstones(inputs) {
+		compaction.Inputs = inputs
+		compaction.Move = true
+		return compaction, true
+	}
+	compaction.Inputs = append(append([]sstable.TableInfo{}, inputs...), overlapping...)
+	return compaction, true
+}
+
+func hasTombstones(tables []sstable.TableInfo) bool {
+	for _, table := range tables {
+		if table.Properties.NumTombstones > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Na nivou ciji se opsezi preklapaju uzima se najstarija tabela, jer su sve ostale
+// tabele koje se sa njom preklapaju novije i mogu ostati iznad nje. Inace se uzima prva
+// tabela ciji je najmanji kljuc veci od kursora, ili prva tabela nivoa kada se dodje do kraja.
+func pickTable(tables []sstable.TableInfo, cursor string) sstable.TableInfo {
+	sorted, ok := sstable.NonOverlapping(tables)
+	if !ok {
+		oldest := tables[0]
+		for _, table := range tables {
+			if table.Sequence < oldest.Sequence {
+				oldest = table
+			}
+		}
+		return oldest
+	}
+	for _, table := range sorted {
+		if table.Properties.SmallestKey > cursor {
+			return table
+		}
+	}
+	return sorted[0]
+}
+
+func loadCursors() map[int]string {
+	cursors := make(map[int]string)
+	file, err := os.Open(CURSORS_PATH)
+	if err != nil {
+		return cursors
+	}
+	defer file.Close()
+	gob.NewDecoder(file).Decode(&cursors)
+	return cursors
+}
+
+func saveCursors(cursors map[int]string) error {
+	err := os.MkdirAll(filepath.Dir(CURSORS_PATH), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(CURSORS_PATH)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gob.NewEncoder(file).Encode(cursors)
+}
@@ -1,218 +1,291 @@
 package lsm_tree
 
 import (
-	"encoding/binary"
-	"os"
+	"container/heap"
 	"projekat_nasp/memTable"
+	"projekat_nasp/merge_operator"
 	"projekat_nasp/sstable"
+	"sort"
+	"sync"
 )
 
-// Spajanje dve ss tabele u jednu novu, first i second su putanje do tabela
-func MergeTables(first, second string, level int) error {
-	// ucitaj fajlove
-	// redom idi kroz fajlove i izvrsavaj merge
-	// napisi novu tabelu
+// Jedna ulazna tabela k-way spajanja i zapis na kom se trenutno nalazi
+type mergeSource struct {
+	it       *sstable.Iterator
+	level    int
+	sequence int64
+	entry    memTable.MemTableEntry
+}
 
-	firstFile, err := os.Open(first)
-	if err != nil {
-		return err
+// Da li je zapis iz a noviji od zapisa iz b za isti kljuc. Odlucuje timestamp, a kada je
+// isti noviji je zapis sa pliceg nivoa, pa na istom nivou onaj iz tabele sa vecim rednim brojem.
+func (a *mergeSource) newerThan(b *mergeSource) bool {
+	if a.entry.GetTimeStamp() != b.entry.GetTimeStamp() {
+		return a.entry.GetTimeStamp() > b.entry.GetTimeStamp()
 	}
-
-	secondFile, err := os.Open(second)
-	if err != nil {
-		return err
+	if a.level != b.level {
+		return a.level < b.level
 	}
+	return a.sequence > b.sequence
+}
 
-	// Dobavljanje duzine data segmenta
-	firstLength, err := getDataSegmentLength(firstFile)
-	if err != nil {
-		return err
-	}
+// Min-heap izvora po kljucu trenutnog zapisa
+type sourceHeap []*mergeSource
 
-	secondLength, err := getDataSegmentLength(secondFile)
-	if err != nil {
-		return err
-	}
+func (h sourceHeap) Len() int { return len(h) }
 
-	// Pozicioniranje na data segment (zaglavlje je 32 bajtova)
-	_, err = firstFile.Seek(32, 0)
-	if err != nil {
-		return err
-	}
+func (h sourceHeap) Less(i, j int) bool {
+	return h[i].entry.GetKey() < h[j].entry.GetKey()
+}
 
-	_, err = secondFile.Seek(32, 0)
-	if err != nil {
-		return err
-	}
+func (h sourceHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
 
-	// Redosledna obrada
-	records := sequentialUpdate(firstFile, secondFile, firstLength, secondLength)
+func (h *sourceHeap) Push(x interface{}) {
+	*h = append(*h, x.(*mergeSource))
+}
 
-	sstable.NewSSTable(&records, level)
+func (h *sourceHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	source := old[n-1]
+	*h = old[:n-1]
+	return source
+}
 
-	err = firstFile.Close()
-	if err != nil {
-		return err
-	}
+/*
+MergeIterator spaja proizvoljan broj tabela u jedan sortiran niz zapisa u jednom prolazu.
+Iz svake tabele se u memoriji drzi samo trenutni zapis, a najmanji kljuc se bira preko heap-a.
+Kada vise tabela ima isti kljuc, vraca se samo najnovija verzija (vidi newerThan). Ako je
+najnovija verzija operand Merge operacije, vraca se njen spoj sa starijim verzijama.
+*/
+type MergeIterator struct {
+	heap     sourceHeap
+	sources  []*mergeSource
+	entry    memTable.MemTableEntry
+	shadowed uint64
+	err      error
+}
 
-	err = secondFile.Close()
-	if err != nil {
-		return err
+func NewMergeIterator(tables []sstable.TableInfo) (*MergeIterator, error) {
+	merge := &MergeIterator{}
+	for _, table := range tables {
+		it, err := sstable.NewIterator(table.Path)
+		if err != nil {
+			merge.Close()
+			return nil, err
+		}
+		source := &mergeSource{it: it, level: table.Level, sequence: table.Sequence}
+		merge.sources = append(merge.sources, source)
+		if merge.advance(source) {
+			merge.heap = append(merge.heap, source)
+		}
 	}
-
-	err = os.Remove(first)
-	if err != nil {
-		return err
+	if merge.err != nil {
+		merge.Close()
+		return nil, merge.err
 	}
+	heap.Init(&merge.heap)
+	return merge, nil
+}
 
-	err = os.Remove(second)
-	if err != nil {
-		return err
+// Pomera izvor na sledeci zapis, vraca false kada je izvor iscrpljen
+func (merge *MergeIterator) advance(source *mergeSource) bool {
+	if !source.it.Next() {
+		if source.it.Err() != nil && merge.err == nil {
+			merge.err = source.it.Err()
+		}
+		return false
 	}
-
-	return nil
+	source.entry = source.it.Entry()
+	return true
 }
 
-func getDataSegmentLength(f *os.File) (int64, error) {
-	b := make([]byte, 8)
+func (merge *MergeIterator) Next() bool {
+	if merge.err != nil || merge.heap.Len() == 0 {
+		return false
+	}
 
-	_, err := f.Read(b)
-	if err != nil {
-		return 0, err
+	newest := heap.Pop(&merge.heap).(*mergeSource)
+	key := newest.entry.GetKey()
+	duplicates := []*mergeSource{newest}
+	for merge.heap.Len() > 0 && merge.heap[0].entry.GetKey() == key {
+		source := heap.Pop(&merge.heap).(*mergeSource)
+		if source.newerThan(newest) {
+			newest = source
+		}
+		duplicates = append(duplicates, source)
+	}
+	merge.entry = newest.entry
+	if newest.entry.GetTombstone() == memTable.MERGE_OPERAND && len(duplicates) > 1 {
+		merge.entry = mergeOperands(duplicates)
 	}
+	merge.shadowed += uint64(len(duplicates) - 1)
 
-	length := int64(binary.LittleEndian.Uint64(b)) - 32 // Oduzimamo zaglavlje
+	for _, source := range duplicates {
+		if merge.advance(source) {
+			heap.Push(&merge.heap, source)
+		}
+	}
+	return merge.err == nil
+}
 
-	return length, nil
+// Spaja operande kljuca sa starijim verzijama iz ostalih tabela, od najnovije ka starijim
+func mergeOperands(duplicates []*mergeSource) memTable.MemTableEntry {
+	sorted := append([]*mergeSource{}, duplicates...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].newerThan(sorted[j])
+	})
+	versions := make([]memTable.MemTableEntry, len(sorted))
+	for i, source := range sorted {
+		versions[i] = source.entry
+	}
+	return memTable.MergeVersions(versions)
 }
 
-type Unit struct {
-	r     *memTable.MemTableEntry
-	end   bool
-	f     *os.File
-	top   int
-	count int
+func (merge *MergeIterator) Entry() memTable.MemTableEntry {
+	return merge.entry
 }
 
-func (u *Unit) isNewer(other Unit) bool {
-	return u.r.GetTimeStamp()> other.r.GetTimeStamp()
+// Broj starijih verzija koje su do sada preskocene jer ih je zamenila novija
+func (merge *MergeIterator) Shadowed() uint64 {
+	return merge.shadowed
 }
 
-func (u *Unit) isAlive() bool {
-	return u.r.GetTombstone()==1
+func (merge *MergeIterator) Err() error {
+	return merge.err
 }
 
-func (u *Unit) nextRecord() {
-	u.count++
-	if u.count > u.top {
-		u.end = true
+func (merge *MergeIterator) Close() {
+	for _, source := range merge.sources {
+		source.it.Close()
 	}
+	merge.sources = nil
+	merge.heap = nil
+}
 
-	if !u.end {
-		rec, _, err := bytesToRecord(u.f)
-		if err != nil {
-			panic(err)
-		}
-		u.r = &rec
-	}
+// Brojaci kompakcija od pokretanja programa
+type CompactionStats struct {
+	TombstonesDropped  uint64
+	TombstonesRetained uint64
+	ShadowedDropped    uint64 // starije verzije kljuca koje je zamenila novija
+	FilterDropped      uint64 // zapisi koje je compaction filter obrisao
+	FilterRewritten    uint64 // zapisi kojima je compaction filter promenio vrednost
 }
 
-func sequentialUpdate(first, second *os.File, firstLength, secondLength int64) []memTable.MemTableEntry {
-	records := make([]memTable.MemTableEntry, 0)
+var (
+	compactionStats      CompactionStats
+	compactionStatsMutex sync.Mutex
+)
 
-	firstRecordsNum := sstable.CountRecords(first.Name())
-	secondRecordsNum := sstable.CountRecords(second.Name())
+func Stats() CompactionStats {
+	compactionStatsMutex.Lock()
+	defer compactionStatsMutex.Unlock()
+	return compactionStats
+}
 
-	firstUnit := Unit{r: nil, end: false, f: first, top: firstRecordsNum, count: 0}
-	secondUnit := Unit{r: nil, end: false, f: second, top: secondRecordsNum, count: 0}
+/*
+Spaja tabele u nove tabele na nivou level i brise ulazne tabele. Ako je targetFileSize > 0
+izlaz se deli na tabele cija data zona ima priblizno toliko bajtova.
+
+Starije verzije kljuca se uvek izbacuju. Tombstone se izbacuje samo kada je dokazano da
+kljuc ne postoji ni u jednoj tabeli ispod izlaza (na nivou level ili dubljem), inace bi
+stara vrednost ponovo postala vidljiva. Na najdubljem nivou to vazi za sve tombstone-ove.
+Isto vazi i za operand Merge operacije: kada ispod izlaza kljuca sigurno nema, operand
+postaje obicna vrednost.
+Ako je postavljen compaction filter, on se poziva za svaki preostali zapis koji nije tombstone.
+*/
+func MergeTables(tables []sstable.TableInfo, level int, targetFileSize uint64) error {
+	below := tablesBelow(tables, level)
+	merge, err := NewMergeIterator(tables)
+	if err != nil {
+		return err
+	}
 
-	for !firstUnit.end || !secondUnit.end {
-		// Prvo citanje
-		if firstUnit.count == 0 && secondUnit.count == 0 {
-			firstUnit.nextRecord()
-			secondUnit.nextRecord()
+	filter := currentCompactionFilter()
+	var dropped, retained, filterDropped, filterRewritten uint64
+	writer := sstable.NewWriter(level, targetFileSize)
+	for merge.Next() {
+		r := merge.Entry()
+		if r.GetTombstone() == memTable.MERGE_OPERAND && !mayExistBelow(below, r.GetKey()) {
+			r = memTable.NewMemTableEntry(r.GetKey(), merge_operator.Configured().Merge(nil, r.GetValue()), 0, r.GetTimeStamp())
 		}
-
-		// Uporedjivanje
-		if firstUnit.r.GetKey() == secondUnit.r.GetKey() {
-			if firstUnit.isNewer(secondUnit) {
-				records = append(records, *firstUnit.r)
-			} else {
-				records = append(records, *secondUnit.r)
+		if r.GetTombstone() == 1 {
+			if !mayExistBelow(below, r.GetKey()) {
+				dropped++
+				continue
 			}
-
-			firstUnit.nextRecord()
-			secondUnit.nextRecord()
-		} else if firstUnit.r.GetKey() > secondUnit.r.GetKey() {
-			if secondUnit.end {
-				records = append(records, *firstUnit.r)
-				firstUnit.nextRecord()
-			} else {
-				records = append(records, *secondUnit.r)
-				secondUnit.nextRecord()
+			retained++
+		} else if filter != nil && r.GetTombstone() == 0 {
+			keep, decision := applyCompactionFilter(filter, level, &r, below)
+			switch decision {
+			case FILTER_DROP:
+				filterDropped++
+			case FILTER_REWRITE:
+				filterRewritten++
 			}
-		} else if secondUnit.r.GetKey() > firstUnit.r.GetKey() {
-			if firstUnit.end {
-				records = append(records, *secondUnit.r)
-				secondUnit.nextRecord()
-			} else {
-				records = append(records, *firstUnit.r)
-				firstUnit.nextRecord()
+			if !keep {
+				continue
 			}
 		}
+		err = addRecord(writer, &r)
+		if err != nil {
+			break
+		}
 	}
-
-	return records
-}
-
-func bytesToRecord(f *os.File) (memTable.MemTableEntry, int64, error) {
-	// Struktura: KS(8), VS(8), TIME(8), TB(1), K(...), V(...)
-	buffer := make([]byte, 8)
-	tombstoneBuffer := make([]byte, 1)
-	// Key size
-	_, err := f.Read(buffer)
-	if err != nil {
-		return memTable.MemTableEntry{}, 0, err
+	if err == nil {
+		err = merge.Err()
 	}
-	keySize := binary.LittleEndian.Uint64(buffer)
-
-	// Value size
-	_, err = f.Read(buffer)
+	merge.Close()
 	if err != nil {
-		return memTable.MemTableEntry{}, 0, err
+		writer.Abort()
+		return err
 	}
-	valueSize := binary.LittleEndian.Uint64(buffer)
-
-	// Timestamp
-	_, err = f.Read(buffer)
+	_, err = writer.Finish()
 	if err != nil {
-		return memTable.MemTableEntry{}, 0, err
+		return err
 	}
-	timestamp := binary.LittleEndian.Uint64(buffer)
 
-	// Tombstone
-	_, err = f.Read(tombstoneBuffer)
-	if err != nil {
-		return memTable.MemTableEntry{}, 0, err
-	}
-	tombstone := tombstoneBuffer[0] 
+	compactionStatsMutex.Lock()
+	compactionStats.TombstonesDropped += dropped
+	compactionStats.TombstonesRetained += retained
+	compactionStats.ShadowedDropped += merge.Shadowed()
+	compactionStats.FilterDropped += filterDropped
+	compactionStats.FilterRewritten += filterRewritten
+	compactionStatsMutex.Unlock()
 
-	// Key
-	keyBuffer := make([]byte, keySize)
-	_, err = f.Read(keyBuffer)
-	if err != nil {
-		return memTable.MemTableEntry{}, 0, err
+	for _, table := range tables {
+		err = removeTable(table.Path)
+		if err != nil {
+			return err
+		}
 	}
-	key := string(keyBuffer)
+	return nil
+}
 
-	// Value
-	value := make([]byte, valueSize)
-	_, err = f.Read(value)
-	if err != nil {
-		return memTable.MemTableEntry{}, 0, err
+// Tabele na nivou level i dubljim koje ne ulaze u spajanje, u njima mogu biti starije verzije kljuceva
+func tablesBelow(inputs []sstable.TableInfo, level int) []sstable.TableInfo {
+	isInput := make(map[string]bool)
+	for _, table := range inputs {
+		isInput[table.Path] = true
+	}
+	var below []sstable.TableInfo
+	for _, table := range sstable.ListTables() {
+		if table.Level >= level && !isInput[table.Path] {
+			below = append(below, table)
+		}
 	}
+	return below
+}
 
-	readBytes := 25 + len(key) + len(value) // 25 je fiksna duzina prvih 4 polja
+func mayExistBelow(below []sstable.TableInfo, key string) bool {
+	for _, table := range below {
+		if sstable.MayContainKey(table, key) {
+			return true
+		}
+	}
+	return false
+}
 
-	return memTable.FillWithParametersEntry(key,value,timestamp,tombstone), int64(readBytes), nil
-}
\ No newline at end of file
+func addRecord(writer *sstable.Writer, r *memTable.MemTableEntry) error {
+	return writer.Add(r.GetKey(), r.GetValue(), sstable.RecordMeta{Timestamp: r.GetTimeStamp(), Tombstone: r.GetTombstone()})
+}
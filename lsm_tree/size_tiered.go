@@ -2,14 +2,17 @@ package lsm_tree
 
 import (
 	"errors"
-	"projekat_nasp/config"
 	"math"
 	"os"
-	"strconv"
+	"projekat_nasp/config"
+	"projekat_nasp/sstable"
 	"strings"
 )
 
-// Size-tiered kompakcija
+// Size-tiered kompakcija: kada nivo dostigne prag (broj tabela ili bajtova), sve njegove tabele
+// se jednim k-way spajanjem prepisuju na sledeci nivo, u tabele od najvise SSTABLE_SIZE bajtova data zone
+type SizeTieredPicker struct{}
+
 func SizeTiered() error {
 	maxLevels := config.GlobalConfig.MaxLevels
 	if maxLevels < 1 {
@@ -26,85 +29,31 @@ func SizeTiered() error {
 		return errors.New("max bytes must be 1024 or more")
 	}
 
-	for lvl := 1; lvl < maxLevels; lvl++ {
-		files, err := getLevelFiles(lvl)
-		if err != nil {
-			return err
+	return RunCompaction(&SizeTieredPicker{})
+}
+
+// Bira prvi nivo, od najpliceg, koji je presao prag
+func (picker *SizeTieredPicker) Pick(tables []sstable.TableInfo) (Compaction, bool) {
+	_, byLevel := sstable.GroupByLevel(tables)
+	for lvl := sstable.FLUSH_LEVEL; lvl < BottomLevel(); lvl++ {
+		files := byLevel[lvl]
+		if len(files) < 2 {
+			continue
 		}
 
-		multiplier := math.Pow(float64(config.GlobalConfig.ScalingFactor), float64(lvl-1))
+		multiplier := math.Pow(float64(config.GlobalConfig.ScalingFactor), math.Max(float64(lvl-1), 0))
+		full := false
 		switch config.GlobalConfig.Condition {
 		case "tables":
-			if len(files) >= maxTables*int(multiplier) {
-				err := compact(files, lvl+1)
-				if err != nil {
-					return err
-				}
-			}
+			full = len(files) >= config.GlobalConfig.MaxTables*int(multiplier)
 		case "bytes":
-			bytes, err := getLevelBytes(files)
-			if err != nil {
-				return err
-			}
-			if bytes >= maxBytes*int(multiplier) {
-				err := compact(files, lvl+1)
-				if err != nil {
-					return err
-				}
-			}
-		}
-	}
-
-	return nil
-}
-
-func getLevelFiles(level int) ([]string, error) {
-	var files []string
-
-	dir, err := os.Open("data/sstable/")
-	defer dir.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	fileInfo, err := dir.Readdir(-1)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, file := range fileInfo {
-		if strings.Contains(file.Name(), "file") {
-			fileLevel := strings.Split(strings.TrimSuffix(file.Name(), ".db"), "_")[2]
-			if lvl, _ := strconv.Atoi(fileLevel); lvl == level {
-				files = append(files, file.Name())
-			}
+			full = tablesBytes(files) >= uint64(float64(config.GlobalConfig.MaxBytes)*multiplier)
 		}
-	}
-
-	return files, nil
-}
-
-func compact(files []string, level int) error {
-	for i := 1; i < len(files); i += 2 {
-		first := "data/sstable/" + files[i-1]
-		second := "data/sstable/" + files[i]
-		err := MergeTables(first, second, level)
-		if err != nil {
-			return err
-		}
-
-		err = deleteMerkleTree(files[i-1])
-		if err != nil {
-			return err
-		}
-
-		err = deleteMerkleTree(files[i])
-		if err != nil {
-			return err
+		if full {
+			return Compaction{Inputs: files, OutputLevel: lvl + 1, TargetFileSize: SSTABLE_SIZE}, true
 		}
 	}
-
-	return nil
+	return Compaction{}, false
 }
 
 func deleteMerkleTree(tableFileName string) error {
@@ -114,19 +63,3 @@ func deleteMerkleTree(tableFileName string) error {
 
 	return err
 }
-
-func getLevelBytes(files []string) (int, error) {
-	total := 0
-
-	for _, file := range files {
-		fi, err := os.Stat("data/sstable/" + file)
-		if err != nil {
-			return 0, err
-		}
-
-		size := fi.Size()
-		total += int(size)
-	}
-
-	return total, nil
-}
\ No newline at end of file
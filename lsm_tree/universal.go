@@ -0,0 +1,118 @@
+package lsm_tree
+
+import (
+	"projekat_nasp/config"
+	"projekat_nasp/sstable"
+)
+
+/*
+Universal kompakcija. Podaci se posmatraju kao niz sortiranih run-ova od najnovijeg ka
+najstarijem: svaka tabela nivoa 0 je jedan run, a svaki neprazan nivo >= 1 je jedan run.
+Kada run-ova ima bar UniversalTrigger, spaja se prefiks najnovijih run-ova:
+  - svi run-ovi, ako je zbir svih osim najstarijeg veci od UniversalMaxAmp procenata
+    najstarijeg (ogranicava prostor koji zauzimaju zastarele verzije)
+  - inace najnoviji run-ovi dok god sledeci run nije veci od zbira prethodnih za vise
+    od UniversalSizeRatio procenata, ako ih je bar UniversalMinMerge
+  - inace toliko najnovijih run-ova da ih ostane UniversalTrigger-1, a najmanje jedan
+*/
+type UniversalPicker struct{}
+
+// Jedan sortiran run: tabele i nivo na kom se nalaze
+type sortedRun struct {
+	level  int
+	tables []sstable.TableInfo
+	size   uint64
+}
+
+func universalOption(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// Run-ovi od najnovijeg ka najstarijem
+func sortedRuns(tables []sstable.TableInfo) []sortedRun {
+	levels, byLevel := sstable.GroupByLevel(tables)
+	var runs []sortedRun
+	for _, lvl := range levels {
+		if lvl == sstable.FLUSH_LEVEL {
+			// tabele su vec od najnovije ka najstarijoj
+			for _, table := range byLevel[lvl] {
+				runs = append(runs, sortedRun{lvl, []sstable.TableInfo{table}, tablesBytes([]sstable.TableInfo{table})})
+			}
+			continue
+		}
+		runs = append(runs, sortedRun{lvl, byLevel[lvl], tablesBytes(byLevel[lvl])})
+	}
+	return runs
+}
+
+func (picker *UniversalPicker) Pick(tables []sstable.TableInfo) (Compaction, bool) {
+	trigger := universalOption(config.GlobalConfig.UniversalTrigger, config.UNIVERSAL_TRIGGER)
+	runs := sortedRuns(tables)
+	if len(runs) < trigger || len(runs) < 2 {
+		return Compaction{}, false
+	}
+
+	// prostorna amplifikacija
+	maxAmp := universalOption(config.GlobalConfig.UniversalMaxAmp, config.UNIVERSAL_MAX_AMP)
+	var newerSize uint64
+	for _, run := range runs[:len(runs)-1] {
+		newerSize += run.size
+	}
+	if newerSize*100 > uint64(maxAmp)*runs[len(runs)-1].size {
+		return universalCompaction(runs, len(runs)), true
+	}
+
+	// odnos velicina
+	sizeRatio := universalOption(config.GlobalConfig.UniversalSizeRatio, config.UNIVERSAL_SIZE_RATIO)
+	minMerge := universalOption(config.GlobalConfig.UniversalMinMerge, config.UNIVERSAL_MIN_MERGE)
+	count := 1
+	candidateSize := runs[0].size
+	for count < len(runs) && runs[count].size*100 <= candidateSize*uint64(100+sizeRatio) {
+		candidateSize += runs[count].size
+		count++
+	}
+	if count >= minMerge && count >= 2 {
+		return universalCompaction(runs, count), true
+	}
+
+	// broj run-ova, za UniversalTrigger 1 ne moze ostati 0 run-ova pa se spajaju svi
+	count = len(runs) - trigger + 2
+	if count > len(runs) {
+		count = len(runs)
+	}
+	return universalCompaction(runs, count), true
+}
+
+/*
+Spaja count najnovijih run-ova. Rezultat mora ostati iznad svih starijih run-ova:
+  - ako je medju ulazima nivo >= 1, izlaz ide na najdublji od njih
+  - ako su ulazi samo deo tabela nivoa 0, izlaz je nova tabela na nivou 0
+  - inace izlaz ide na nivo neposredno iznad sledeceg starijeg run-a (ili na najdublji nivo)
+*/
+func universalCompaction(runs []sortedRun, count int) Compaction {
+	var inputs []sstable.TableInfo
+	for _, run := range runs[:count] {
+		inputs = append(inputs, run.tables...)
+	}
+
+	outputLevel := runs[count-1].level
+	if outputLevel == sstable.FLUSH_LEVEL {
+		switch {
+		case count == len(runs):
+			outputLevel = BottomLevel()
+		case runs[count].level == sstable.FLUSH_LEVEL:
+			outputLevel = sstable.FLUSH_LEVEL
+		default:
+			outputLevel = runs[count].level - 1
+		}
+	}
+
+	compaction := Compaction{Inputs: inputs, OutputLevel: outputLevel}
+	if outputLevel != sstable.FLUSH_LEVEL {
+		compaction.TargetFileSize = SSTABLE_SIZE
+	}
+	return compaction
+}
@@ -5,16 +5,11 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
-	"projekat_nasp/bloom_filter"
-	"projekat_nasp/cache"
 	"projekat_nasp/config"
-	"projekat_nasp/countMinSketch"
-	"projekat_nasp/hyperloglog"
+	"projekat_nasp/engine"
 	"projekat_nasp/lsm_tree"
 	"projekat_nasp/memTable"
-	"projekat_nasp/simhash"
 	"projekat_nasp/sstable"
-	"projekat_nasp/token_bucket"
 	"projekat_nasp/util"
 	"projekat_nasp/wal"
 	"strconv"
@@ -24,8 +19,8 @@ import (
 
 func main() {
 
-	myWal, memtable, tokenBucket, cache, bloom_filter, hll, cms, simhash := Start()
-	fmt.Println(bloom_filter, simhash) //Dodato da ne bi ispisivao gresku da nisu korisceni
+	engine := Start()
+	client := engine.Client(CLIENT)
 	for {
 		fmt.Println("1. GET")
 		fmt.Println("2. PUT")
@@ -38,157 +33,207 @@ func main() {
 		fmt.Println("9. With compression")
 		fmt.Println("10. Without compression")
 		fmt.Println("11. Exit")
+		fmt.Println("12. SSTable properties")
+		fmt.Println("13. Ingest SSTables")
+		fmt.Println("14. MERGE")
+		fmt.Println("15. Set flush/compaction write limit")
+		fmt.Println("16. Hot keys")
+		fmt.Println("17. Sketch command (BF/CMS/HLL/SIMHASH)")
+		fmt.Println("18. Approximate number of keys with prefix (upper bound)")
+		fmt.Println("19. Find near-duplicate values")
+		fmt.Println("20. Value size and latency percentiles")
 
 		fmt.Print("Enter your choice: ")
 
 		var choice int
 		fmt.Scan(&choice)
 
-		if tokenBucket.CheckRequest() {
-			switch choice {
-			case 1: // GET
-				fmt.Print("Enter key: ")
-				var key string
-				fmt.Scan(&key)
-				key = strings.TrimRight(key, "\n")
-
-				foundMemTable, valueMemTable := memtable.Find(key)
-
-				if foundMemTable {
-					fmt.Println("Nasao u memtable-u: ", valueMemTable)
-					cache.AddItem(valueMemTable.GetKey(), string(valueMemTable.GetValue()))
-				} else {
-					foundCache, valueCache := cache.GetByKey(key)
-					if foundCache {
-						fmt.Println("Nasao u cache-u: ", valueCache)
-					} else {
-						entry := sstable.Main_search([]string{key})
-						if len(entry) == 0 {
-							fmt.Println("Neuspesna pretraga")
-						} else {
-							for i := 0; i < len(entry); i++ {
-								fmt.Println("Nasao u sstable-u: ", entry[i])
-								cache.AddItem(entry[i].GetKey(), string(entry[i].GetValue()))
-							}
-						}
-					}
-				}
-
-			case 2: // PUT
-				fmt.Print("Enter key: ")
-				var key string
-				fmt.Scan(&key)
-
-				fmt.Print("Enter value: ")
-				var value string
-				fmt.Scan(&value)
-
-				walEntry := myWal.Write(key, []byte(value), 0)
-				entry := memTable.NewMemTableEntry(key, []byte(value), 0, walEntry.Timestamp)
-				full, sizeToDelete := memtable.Add(entry)
-				if full != nil {
-					if config.GlobalConfig.SStableAllInOne == false {
-						if config.GlobalConfig.SStableDegree != 0 {
-							sstable.CreateSStable_13(full, 1, config.GlobalConfig.SStableDegree)
-						} else {
-							sstable.CreateSStable(full, 1)
-						}
-					} else {
-						sstable.NewSSTable(&full, 1)
-					}
-					//fmt.Println(sizeToDelete) // Ovde treba pozvati brisanje wal segmenata
-					myWal.DeleteBytesFromFiles(sizeToDelete)
-				}
-				cache.AddItem(key, value)
-				hll.Add(key)
-				cms.AddKey(key)
-			case 3: // DELETE
-				fmt.Print("Enter key: ")
-				var key string
-				fmt.Scan(&key)
-
-				myWal.Delete(key, 1)
-				memtable.Delete(key)
-				cache.DeleteByKey(key)
-
-			case 4: // EXIT
-				fmt.Print("Enter key: ")
-				var key string
-				fmt.Scan(&key)
-				cardinality := cms.FindKeyFrequency(key)
-				fmt.Printf("Estimated cardinality of key %s: %d \n", key, cardinality)
-			case 5:
-				cardinality := hll.Prebroj()
-				fmt.Printf("Estimated cardinality: %f \n", cardinality)
-			case 6: //COMPACT
-				if config.GlobalConfig.CompactionAlgorithm == "sizeTiered" {
-					err := lsm_tree.SizeTiered()
-					if err != nil {
-						fmt.Println(err)
-					}
-				} else {
-					lsm_tree.LeveledCompaction()
-				}
-			case 7:
-				fmt.Print("Enter a prefix: ")
-				var c string
-				fmt.Scan(&c)
-				fmt.Print("Enter a page number: ")
-				var a int
-				fmt.Scan(&a)
-				fmt.Print("Enter a page size: ")
-				var b int
-				fmt.Scan(&b)
-				memtable.PrefixScan(c, a, b)
-			case 9:
-				Test_DZ3_compression(100)
-			case 10:
-				Test_DZ3_without_compression(100)
-
-			case 11: //EXIT
-				fmt.Println("Exiting...")
-				//data := memtable.Sort()
-				//sstable.CreateSStable(data, 1)
-				//bloom_filter.SaveToFile("data/bloom_filter/bf.gob")
-				hll.SacuvajHLL("./data/hyperloglog/hll.gob")
-				countMinSketch.WriteGob("./data/count_min_sketch/cms.gob", cms)
-				//simhash.SerializeSH()
-				os.Exit(0)
-			default:
-				fmt.Println("Invalid choice. Please enter a valid option.")
-				//memtable.Print()
+		switch choice {
+		case 1: // GET
+			fmt.Print("Enter key: ")
+			var key string
+			fmt.Scan(&key)
+			key = strings.TrimRight(key, "\n")
+
+			value, found, err := client.Get(key)
+			if err != nil {
+				fmt.Println(err)
+			} else if found {
+				fmt.Println("Nasao: ", string(value))
+			} else {
+				fmt.Println("Neuspesna pretraga")
 			}
 
+		case 2: // PUT
+			fmt.Print("Enter key: ")
+			var key string
+			fmt.Scan(&key)
+
+			fmt.Print("Enter value: ")
+			var value string
+			fmt.Scan(&value)
+
+			err := client.Put(key, []byte(value))
+			if err != nil {
+				fmt.Println(err)
+			}
+		case 3: // DELETE
+			fmt.Print("Enter key: ")
+			var key string
+			fmt.Scan(&key)
+
+			err := client.Delete(key)
+			if err != nil {
+				fmt.Println(err)
+			}
+
+		case 4: // EXIT
+			fmt.Print("Enter key: ")
+			var key string
+			fmt.Scan(&key)
+			cardinality := engine.KeyFrequency(key)
+			fmt.Printf("Estimated cardinality of key %s: %d \n", key, cardinality)
+		case 5:
+			cardinality := engine.KeyCardinality()
+			fmt.Printf("Estimated cardinality: %f \n", cardinality)
+		case 6: //COMPACT
+			err := lsm_tree.Compact()
+			if err != nil {
+				fmt.Println(err)
+			}
+			stats := lsm_tree.Stats()
+			fmt.Printf("Tombstones dropped: %d, retained: %d, shadowed versions dropped: %d \n", stats.TombstonesDropped, stats.TombstonesRetained, stats.ShadowedDropped)
+			fmt.Printf("Compaction filter dropped: %d, rewritten: %d \n", stats.FilterDropped, stats.FilterRewritten)
+		case 7:
+			fmt.Print("Enter a prefix: ")
+			var c string
+			fmt.Scan(&c)
+			fmt.Print("Enter a page number: ")
+			var a int
+			fmt.Scan(&a)
+			fmt.Print("Enter a page size: ")
+			var b int
+			fmt.Scan(&b)
+			err := client.PrefixScan(c, a, b)
+			if err != nil {
+				fmt.Println(err)
+			}
+		case 9:
+			Test_DZ3_compression(100)
+		case 10:
+			Test_DZ3_without_compression(100)
+
+		case 11: //EXIT
+			fmt.Println("Exiting...")
+			//data := memtable.Sort()
+			//sstable.CreateSStable(data, 1)
+			engine.Close()
+			os.Exit(0)
+		case 12:
+			sstable.PrintProperties()
+		case 13:
+			fmt.Print("Enter table paths separated by commas: ")
+			var paths string
+			fmt.Scan(&paths)
+			err := engine.IngestFiles(strings.Split(paths, ","))
+			if err != nil {
+				fmt.Println(err)
+			}
+		case 14:
+			fmt.Print("Enter key: ")
+			var key string
+			fmt.Scan(&key)
+
+			fmt.Print("Enter operand: ")
+			var operand string
+			fmt.Scan(&operand)
+
+			err := client.Merge(key, []byte(operand))
+			if err != nil {
+				fmt.Println(err)
+			}
+		case 15:
+			fmt.Print("Enter bytes per second (0 for no limit): ")
+			var limit int64
+			fmt.Scan(&limit)
+			sstable.SetIORateLimit(limit)
+		case 16:
+			fmt.Print("Enter number of keys: ")
+			var k int
+			fmt.Scan(&k)
+			for _, hitter := range engine.HotKeys(k) {
+				fmt.Printf("%s: %d \n", hitter.Key, hitter.Count)
+			}
+		case 17:
+			fmt.Print("Enter command (e.g. BF.ADD name item): ")
+			line := readLine()
+			if strings.TrimSpace(line) == "" {
+				line = readLine()
+			}
+			reply, err := client.Execute(strings.Fields(line))
+			if err != nil {
+				fmt.Println(err)
+			} else {
+				fmt.Println(reply)
+			}
+		case 18:
+			fmt.Print("Enter a prefix: ")
+			var prefix string
+			fmt.Scan(&prefix)
+			count, err := engine.Count(prefix)
+			if err != nil {
+				fmt.Println(err)
+			} else {
+				fmt.Printf("Estimated number of keys with prefix %s: %d (deleted keys are counted until compaction)\n", prefix, count)
+			}
+		case 19:
+			fmt.Print("Enter key or text: ")
+			query := readLine()
+			if strings.TrimSpace(query) == "" {
+				query = readLine()
+			}
+			fmt.Print("Enter max distance: ")
+			var maxDistance int
+			fmt.Scan(&maxDistance)
+			keys, err := client.FindNearDuplicates(strings.TrimSpace(query), maxDistance)
+			if err != nil {
+				fmt.Println(err)
+			} else {
+				fmt.Println(keys)
+			}
+		case 20:
+			stats := engine.Stats()
+			fmt.Printf("value size (B):  n=%d p50=%.0f p95=%.0f p99=%.0f \n", stats.ValueSize.Count, stats.ValueSize.P50, stats.ValueSize.P95, stats.ValueSize.P99)
+			fmt.Printf("GET latency (us): n=%d p50=%.1f p95=%.1f p99=%.1f \n", stats.GetLatency.Count, stats.GetLatency.P50, stats.GetLatency.P95, stats.GetLatency.P99)
+			fmt.Printf("PUT latency (us): n=%d p50=%.1f p95=%.1f p99=%.1f \n", stats.PutLatency.Count, stats.PutLatency.P50, stats.PutLatency.P95, stats.PutLatency.P99)
+		default:
+			fmt.Println("Invalid choice. Please enter a valid option.")
+			//memtable.Print()
 		}
 	}
 }
 
-func Start() (*wal.Wal, memTable.MemTablesManager, *token_bucket.TokenBucket, *cache.Cache, *bloom_filter.BloomFilter, hyperloglog.HLL, *countMinSketch.CountMinSketch, *simhash.SimHash) {
+// Ime klijenta u cije ime meni pristupa bazi, njegove kofe ogranicavaju zahteve
+const CLIENT = "cli"
+
+func Start() *engine.Engine {
 
 	config.Init()
 
-	myWal := wal.NewWal()
-	var memtable memTable.MemTablesManager
-	switch config.GlobalConfig.StructureType {
-	case "hashmap":
-		memtable = memTable.InitMemTablesHash(config.GlobalConfig.MaxTables, uint64(config.GlobalConfig.MemtableSize))
-	case "btree":
-		memtable = memTable.InitMemTablesBTree(config.GlobalConfig.MaxTables, uint64(config.GlobalConfig.MemtableSize), uint8(config.GlobalConfig.BTreeOrder))
-	case "skiplist":
-		memtable = memTable.InitMemTablesSkipList(config.GlobalConfig.MaxTables, uint64(config.GlobalConfig.MemtableSize), config.GlobalConfig.SkipListHeight)
+	return engine.NewEngine()
+}
+
+// Cita ostatak linije sa standardnog ulaza bajt po bajt, da ne bi preuzeo ulaz za sledeci fmt.Scan
+func readLine() string {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if n == 0 || err != nil || b[0] == '\n' {
+			return string(line)
+		}
+		line = append(line, b[0])
 	}
-	tokenBucket := token_bucket.NewTokenBucket(1, 5)
-	cache := cache.NewCache(10)
-
-	///treba srediti imena fajlova i dodati za errore
-	myWal.Recovery(&memtable)
-	bloom_filter, _ := bloom_filter.LoadFromFile("data/bloom_filter/bf.gob")
-	hll := hyperloglog.UcitajHLL("./data/hyperloglog/hll.gob")
-	var cms = new(countMinSketch.CountMinSketch)
-	_ = countMinSketch.ReadGob("./data/count_min_sketch/cms.gob", cms)
-	simhash, _ := simhash.DeserializeSH([]byte("???"))
-
-	return myWal, memtable, tokenBucket, cache, bloom_filter, hll, cms, simhash
 }
 
 func asciiToText(asciiValues []int) string {
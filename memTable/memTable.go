@@ -4,7 +4,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"os"
+	"path/filepath"
 	"projekat_nasp/config"
+	"projekat_nasp/cuckoo_filter"
+	"projekat_nasp/merge_operator"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -24,6 +27,18 @@ type MemTable interface {
 	Print()
 }
 
+// Prefiks rezervisan za interne kljuceve baze (stanje token bucket-a i sl.). Takvi kljucevi
+// se cuvaju kao i ostali, ali se ne vide kroz korisnicke operacije i skeniranja.
+const INTERNAL_KEY_PREFIX = "\x00sys/"
+
+func IsInternalKey(key string) bool {
+	return strings.HasPrefix(key, INTERNAL_KEY_PREFIX)
+}
+
+// Vrednost polja tombstone za operand Merge operacije (0 je obicna vrednost, 1 brisanje).
+// Operand se ne tumaci sam za sebe, vec se operatorom spaja sa starijom vrednoscu kljuca.
+const MERGE_OPERAND byte = 2
+
 type MemTableEntry struct {
 	key       string
 	value     []byte
@@ -76,6 +91,18 @@ type MemTablesManager struct {
 	walSize      []int
 	maxInstances int
 	active       int
+	keys         *cuckoo_filter.CuckooFilter // kljucevi koji mogu biti u memtabelama, nil ako je iskljuceno
+	keysFull     bool                        // filter je pun, ne koristi se do praznjenja svih memtabela
+}
+
+// Cuckoo filter kljuceva svih memtabela (config.MemtableFilter). Kljuc se dodaje kada prvi put
+// udje u aktivnu memtabelu, a brise se kada se ta memtabela isprazni, pa Find za kljuceve kojih
+// nema u memtabelama ne pretrazuje ni jednu od njih.
+func newKeyFilter(maxInstances int, maxSize uint64) *cuckoo_filter.CuckooFilter {
+	if !config.GlobalConfig.MemtableFilter {
+		return nil
+	}
+	return cuckoo_filter.NewCuckooFilter(maxInstances * int(maxSize))
 }
 
 func InitMemTablesHash(maxInstances int, maxSize uint64) MemTablesManager {
@@ -95,6 +122,8 @@ func InitMemTablesHash(maxInstances int, maxSize uint64) MemTablesManager {
 		walSize,
 		maxInstances,
 		0,
+		newKeyFilter(maxInstances, maxSize),
+		false,
 	}
 	return memTables
 }
@@ -120,6 +149,8 @@ func InitMemTablesBTree(maxInstances int, maxSize uint64, order uint8) MemTables
 		walSize,
 		maxInstances,
 		0,
+		newKeyFilter(maxInstances, maxSize),
+		false,
 	}
 	return memTables
 }
@@ -145,6 +176,8 @@ func InitMemTablesSkipList(maxInstances int, maxSize uint64, maxHeight int) MemT
 		walSize,
 		maxInstances,
 		0,
+		newKeyFilter(maxInstances, maxSize),
+		false,
 	}
 	return memTables
 }
@@ -152,14 +185,25 @@ func InitMemTablesSkipList(maxInstances int, maxSize uint64, maxHeight int) MemT
 // Adds entry to active memTable, if all are full returns them sorted as a sign to flush to SSTable
 func (memTables *MemTablesManager) Add(entry MemTableEntry) ([]MemTableEntry, int) {
 	activeTable := memTables.tables[memTables.active]
-	activeTable.Add(entry)
 	memTables.walSize[memTables.active] += 29 + len([]byte(entry.GetKey())) + len(entry.GetValue())
+	if entry.tombstone == MERGE_OPERAND {
+		entry = memTables.combineOperand(entry)
+	}
+	if memTables.keys != nil && !memTables.keysFull && activeTable.Find(entry.key).key == "" {
+		memTables.keysFull = !memTables.keys.Insert(entry.key)
+	}
+	activeTable.Add(entry)
 	fmt.Println(memTables.walSize[memTables.active])
 	if activeTable.IsFull() {
 		nextTable := (memTables.active + 1) % memTables.maxInstances
 		if memTables.tables[nextTable].IsFull() {
 			sorted := memTables.tables[nextTable].Sort()
 			memTables.tables[nextTable].Reset()
+			if memTables.keys != nil && !memTables.keysFull {
+				for _, flushed := range sorted {
+					memTables.keys.Delete(flushed.key)
+				}
+			}
 			memTables.active = nextTable
 			fmt.Println(sorted)
 			toDelete := memTables.walSize[memTables.active]
@@ -171,12 +215,55 @@ func (memTables *MemTablesManager) Add(entry MemTableEntry) ([]MemTableEntry, in
 	return nil, 0
 }
 
+// Memtabela cuva jedan zapis po kljucu, pa se novi operand odmah spaja sa poslednjim
+// zapisom kljuca u memtabelama. Vrednosti iz SSTabela se ovde ne citaju.
+func (memTables *MemTablesManager) combineOperand(entry MemTableEntry) MemTableEntry {
+	found, older := memTables.Find(entry.key)
+	if !found {
+		return entry
+	}
+	return MergeVersions([]MemTableEntry{entry, older})
+}
+
+/*
+Spaja verzije jednog kljuca date od najnovije ka starijim, kada je najnovija operand Merge
+operacije. Operandi se spajaju od najstarijeg ka najnovijem preko prve verzije koja nije
+operand: ako je to vrednost ili brisanje, rezultat je obicna vrednost. Ako takve verzije nema,
+rezultat je opet operand, jer se vrednost kljuca moze nalaziti u nekoj starijoj tabeli.
+Rezultat dobija timestamp najnovije verzije.
+*/
+func MergeVersions(versions []MemTableEntry) MemTableEntry {
+	newest := versions[0]
+	var base []byte
+	tombstone := MERGE_OPERAND
+	var operands [][]byte
+	for _, version := range versions {
+		if version.tombstone != MERGE_OPERAND {
+			if version.tombstone == 0 {
+				base = version.value
+			}
+			tombstone = 0
+			break
+		}
+		operands = append(operands, version.value)
+	}
+	for i, j := 0, len(operands)-1; i < j; i, j = i+1, j-1 {
+		operands[i], operands[j] = operands[j], operands[i]
+	}
+	value := merge_operator.Fold(merge_operator.Configured(), base, operands)
+	return NewMemTableEntry(newest.key, value, tombstone, newest.timestamp)
+}
+
 // Resets all memtables to empty them after sort
 func (memTables *MemTablesManager) Reset() {
 	for i := 0; i < memTables.maxInstances; i++ {
 		memTables.tables[i].Reset()
 	}
 	memTables.active = 0
+	if memTables.keys != nil {
+		memTables.keys.Reset()
+		memTables.keysFull = false
+	}
 }
 
 func (memTables *MemTablesManager) Delete(key string) {
@@ -184,6 +271,9 @@ func (memTables *MemTablesManager) Delete(key string) {
 }
 
 func (memTables *MemTablesManager) Find(key string) (bool, MemTableEntry) {
+	if memTables.keys != nil && !memTables.keysFull && !memTables.keys.Contains(key) {
+		return false, MemTableEntry{}
+	}
 	activeTable := memTables.tables[memTables.active]
 	found := activeTable.Find(key)
 	if found.key != "" {
@@ -217,6 +307,24 @@ func (memTables *MemTablesManager) Sort() [][]MemTableEntry {
 	return sortedAll
 }
 
+// Prazni sve memtabele, od najstarije do aktivne. Vraca njihov sortiran sadrzaj
+// i broj bajtova WAL-a koji posle upisa u SSTabele vise nije potreban.
+func (memTables *MemTablesManager) FlushAll() ([][]MemTableEntry, int) {
+	var flushed [][]MemTableEntry
+	toDelete := 0
+	for j := 1; j <= memTables.maxInstances; j++ {
+		i := (memTables.active + j) % memTables.maxInstances
+		sorted := memTables.tables[i].Sort()
+		if len(sorted) > 0 {
+			flushed = append(flushed, sorted)
+		}
+		toDelete += memTables.walSize[i]
+		memTables.walSize[i] = 0
+	}
+	memTables.Reset()
+	return flushed, toDelete
+}
+
 func (memTables *MemTablesManager) IsFull() bool {
 	return false
 }
@@ -282,24 +390,10 @@ func BytesToRecord(f *os.File) (MemTableEntry, int64, error) {
 
 // Prints only part of entries whose keys contain defined prefix.
 // This is like a book: each page has the same number of keys, and only keys from one page will be printed.
-func (memTables *MemTablesManager) PrefixScan(prefix string, pageNumber int, pageSize int) {
+// Only SSTables from tablePaths are read, the caller leaves out tables that cannot hold the prefix.
+func (memTables *MemTablesManager) PrefixScan(prefix string, pageNumber int, pageSize int, tablePaths []string) {
 	fmt.Print("Content of page ", pageNumber, ": ")
 
-	dirPath := "data/sstable"
-
-	dir, err := os.Open(dirPath)
-	if err != nil {
-		fmt.Println("Error opening directory:", err)
-		return
-	}
-	defer dir.Close()
-
-	fileInfos, err := dir.Readdir(-1) //-1 is sign to read all filenames from the directory
-	if err != nil {
-		fmt.Println("Error reading directory contents:", err)
-		return
-	}
-
 	const MAX_SSTABLES = 3000
 	offsetArray := make([]int64, MAX_SSTABLES) // array of offsets for each file
 	var filePositionArray [MAX_SSTABLES]int64
@@ -328,11 +422,11 @@ func (memTables *MemTablesManager) PrefixScan(prefix string, pageNumber int, pag
 		var minLens []int64  // extra offsets in files with minKey (equals to length of this entry)
 
 		// SSTABLES SCANNER (from files - disk)
-		for _, fileInfo := range fileInfos {
-			// each file starting with "file_" contains data from one SSTable
+		for _, tablePath := range tablePaths {
+			// each path is one SSTable
 
-			if strings.HasPrefix(fileInfo.Name(), "file_") {
-				file, err := os.OpenFile("data/sstable/"+fileInfo.Name(), os.O_RDONLY, 0600)
+			if strings.HasPrefix(filepath.Base(tablePath), "file_") {
+				file, err := os.OpenFile(tablePath, os.O_RDONLY, 0600)
 				if err != nil {
 					panic(err)
 				}
@@ -387,10 +481,12 @@ func (memTables *MemTablesManager) PrefixScan(prefix string, pageNumber int, pag
 			break // all files are read - break the outer loop
 		}
 
-		if keyOrdinalNum >= startKey && keyOrdinalNum <= endKey {
-			fmt.Print(minKey, " ")
+		if !IsInternalKey(minKey) {
+			if keyOrdinalNum >= startKey && keyOrdinalNum <= endKey {
+				fmt.Print(minKey, " ")
+			}
+			keyOrdinalNum++
 		}
-		keyOrdinalNum++
 
 		for j := 0; j < len(minLens); j++ {
 			offsetArray[minIndices[j]] += minLens[j]
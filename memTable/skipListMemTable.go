@@ -41,7 +41,10 @@ func (table *skipListMemTable) Delete(key string) {
 }
 
 func (table *skipListMemTable) Find(key string) MemTableEntry {
-	entry, _ := table.data.SearchElement(key)
+	entry, found := table.data.SearchElement(key)
+	if !found {
+		return MemTableEntry{}
+	}
 	return *entry
 }
 
@@ -0,0 +1,117 @@
+package merge_operator
+
+import (
+	"bytes"
+	"projekat_nasp/config"
+	"strconv"
+	"sync"
+)
+
+/*
+Asocijativan operator za Merge(key, operand). Merge(left, right) spaja stariju vrednost left
+sa novijim operandom right; left je nil kada ispod operanda nema vrednosti (kljuc ne postoji
+ili je obrisan). Posto je operator asocijativan, rezultat spajanja dva operanda je opet operand,
+pa se operandi mogu spajati i kada osnovna vrednost jos nije poznata.
+*/
+type MergeOperator interface {
+	Name() string
+	Merge(left, right []byte) []byte
+}
+
+var (
+	operators = map[string]MergeOperator{
+		"int64Add":     Int64Add{},
+		"stringAppend": StringAppend{Delimiter: ","},
+		"max":          Max{},
+	}
+	operatorsMutex sync.Mutex
+)
+
+// Dodaje korisnicki operator, posle cega se moze izabrati imenom iz config.MergeOperator
+func Register(operator MergeOperator) {
+	operatorsMutex.Lock()
+	defer operatorsMutex.Unlock()
+	operators[operator.Name()] = operator
+}
+
+func Get(name string) (MergeOperator, bool) {
+	operatorsMutex.Lock()
+	defer operatorsMutex.Unlock()
+	operator, ok := operators[name]
+	return operator, ok
+}
+
+// Operator iz konfiguracije, a ako ime nije zadato ili nije poznato config.MERGE_OPERATOR
+func Configured() MergeOperator {
+	if operator, ok := Get(config.GlobalConfig.MergeOperator); ok {
+		return operator
+	}
+	operator, _ := Get(config.MERGE_OPERATOR)
+	return operator
+}
+
+// Spaja operande od najstarijeg ka najnovijem preko osnovne vrednosti base (nil ako je nema)
+func Fold(operator MergeOperator, base []byte, operands [][]byte) []byte {
+	result := base
+	for _, operand := range operands {
+		result = operator.Merge(result, operand)
+	}
+	return result
+}
+
+// Brojac: vrednosti su celi brojevi zapisani dekadno, neispravna vrednost se racuna kao 0
+type Int64Add struct{}
+
+func (Int64Add) Name() string { return "int64Add" }
+
+func (Int64Add) Merge(left, right []byte) []byte {
+	return []byte(strconv.FormatInt(parseInt(left)+parseInt(right), 10))
+}
+
+// Nadovezivanje stringova, izmedju starije i novije vrednosti se umece Delimiter
+type StringAppend struct {
+	Delimiter string
+}
+
+func (StringAppend) Name() string { return "stringAppend" }
+
+func (operator StringAppend) Merge(left, right []byte) []byte {
+	if left == nil {
+		return append([]byte{}, right...)
+	}
+	merged := make([]byte, 0, len(left)+len(operator.Delimiter)+len(right))
+	merged = append(merged, left...)
+	merged = append(merged, operator.Delimiter...)
+	return append(merged, right...)
+}
+
+// Veca od dve vrednosti: brojcano ako su obe celi brojevi, inace po bajtovima
+type Max struct{}
+
+func (Max) Name() string { return "max" }
+
+func (Max) Merge(left, right []byte) []byte {
+	if left == nil {
+		return right
+	}
+	l, errLeft := strconv.ParseInt(string(left), 10, 64)
+	r, errRight := strconv.ParseInt(string(right), 10, 64)
+	if errLeft == nil && errRight == nil {
+		if l >= r {
+			return left
+		}
+		return right
+	}
+	if bytes.Compare(left, right) >= 0 {
+		return left
+	}
+	return right
+}
+
+func parseInt(value []byte) int64 {
+	n, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,135 @@
+package merkletree
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+const HASH_SIZE = sha1.Size
+
+/*
+Builder pravi isto stablo kao BuildMerkleTree, ali bez drzanja listova u memoriji. Svaki nivo
+stabla se pise u svoj pomocni fajl cim se cvor izracuna: kada nivo dobije par cvorova, njihov
+roditelj odmah ide u sledeci nivo, pa u memoriji ostaje najvise jedan cvor po nivou koji ceka
+par. Finish zatvara nivoe sa neparnim brojem cvorova (poslednji cvor se spaja sam sa sobom) i
+ispisuje stablo iz pomocnih fajlova istim redosledom kao SerializeMerkleTree.
+*/
+type Builder struct {
+	path   string
+	levels []*builderLevel
+}
+
+type builderLevel struct {
+	file    *os.File
+	writer  *bufio.Writer
+	count   uint64
+	pending []byte // levi cvor koji jos nema par
+}
+
+// Stablo ce biti upisano u path, a pomocni fajlovi nivoa su path.<nivo>.tmp
+func NewBuilder(path string) *Builder {
+	return &Builder{path: path}
+}
+
+// Dodaje sledeci list, hes od data
+func (b *Builder) Add(data []byte) error {
+	return b.push(0, Hash(data))
+}
+
+func (b *Builder) push(level int, hash []byte) error {
+	if level == len(b.levels) {
+		file, err := os.Create(b.path + "." + fmt.Sprint(level) + ".tmp")
+		if err != nil {
+			return err
+		}
+		b.levels = append(b.levels, &builderLevel{file: file, writer: bufio.NewWriter(file)})
+	}
+	l := b.levels[level]
+	_, err := l.writer.Write(hash)
+	if err != nil {
+		return err
+	}
+	l.count++
+	if l.pending == nil {
+		l.pending = hash
+		return nil
+	}
+	parent := Hash(append(l.pending, hash...))
+	l.pending = nil
+	return b.push(level+1, parent)
+}
+
+// Zavrsava stablo i upisuje ga u fajl, bez listova fajl se ne pravi
+func (b *Builder) Finish() error {
+	defer b.Abort()
+	if len(b.levels) == 0 {
+		return nil
+	}
+	for level := 0; level < len(b.levels); level++ {
+		l := b.levels[level]
+		if level == len(b.levels)-1 && l.count == 1 {
+			break
+		}
+		if l.pending != nil {
+			pending := l.pending
+			l.pending = nil
+			err := b.push(level+1, Hash(append(pending, pending...)))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, l := range b.levels {
+		err := l.writer.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Create(b.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	err = b.serialize(writer, len(b.levels)-1, 0)
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// Ispisuje cvor i njegovo podstablo: cvor, levo pa desno podstablo. Deca cvora i na nivou
+// iznad su 2i i 2i+1, a ako 2i+1 ne postoji desno dete je ponovo 2i.
+func (b *Builder) serialize(writer *bufio.Writer, level int, i uint64) error {
+	hash := make([]byte, HASH_SIZE)
+	_, err := b.levels[level].file.ReadAt(hash, int64(i*HASH_SIZE))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(writer, hex.EncodeToString(hash))
+	if err != nil || level == 0 {
+		return err
+	}
+	left, right := 2*i, 2*i+1
+	if right >= b.levels[level-1].count {
+		right = left
+	}
+	err = b.serialize(writer, level-1, left)
+	if err != nil {
+		return err
+	}
+	return b.serialize(writer, level-1, right)
+}
+
+// Brise pomocne fajlove, stablo se ne upisuje
+func (b *Builder) Abort() {
+	for _, l := range b.levels {
+		l.file.Close()
+		os.Remove(l.file.Name())
+	}
+	b.levels = nil
+}
@@ -41,13 +41,23 @@ func SerializeMerkleTree(root *Node, file *os.File) {
 }
 
 func BuildMerkleTree(data [][]byte, unixTime int64) {
-	if len(data) == 0 {
+	var hashes [][]byte
+	for _, d := range data {
+		hashes = append(hashes, Hash(d))
+	}
+	BuildMerkleTreeFromHashes(hashes, "data/sstable/MetaData_"+fmt.Sprint(unixTime)+".txt")
+}
+
+// Isto kao BuildMerkleTree, ali listovi su vec hesirani pa pozivalac ne mora da cuva same podatke.
+// Stablo se upisuje u fajl path.
+func BuildMerkleTreeFromHashes(hashes [][]byte, path string) {
+	if len(hashes) == 0 {
 		return
 	}
 
 	var nodes []*Node
-	for _, d := range data {
-		nodes = append(nodes, &Node{data: Hash(d)})
+	for _, h := range hashes {
+		nodes = append(nodes, &Node{data: h})
 	}
 
 	for len(nodes) > 1 {
@@ -68,7 +78,7 @@ func BuildMerkleTree(data [][]byte, unixTime int64) {
 		nodes = newNodes
 	}
 
-	file, _ := os.Create("data/sstable/MetaData_" + fmt.Sprint(unixTime) + ".txt")
+	file, _ := os.Create(path)
 	defer file.Close()
 	root := &MerkleRoot{root: nodes[0]}
 	SerializeMerkleTree(root.root, file)
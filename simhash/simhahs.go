@@ -1,140 +1,211 @@
 package simhash
 
 import (
-	"bytes"
-	"crypto/md5"
-	"encoding/gob"
-	"encoding/hex"
-	"fmt"
-	"strconv"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math/bits"
 	"strings"
+	"unicode"
 )
 
+/*
+SimHash teksta je 64-bitni otisak: tekst se deli na tokene, svaki token se hesira u 64 bita,
+pa se za svaki bit tezina tokena dodaje ako je bit 1, a oduzima ako je 0. Bit otiska je 1
+kada je zbir pozitivan. Slicni tekstovi dele vecinu tokena, pa se otisci razlikuju u malo
+bitova, a rastojanje je broj bitova u kojima se razlikuju.
+*/
 type SimHash struct {
 	text        string
-	words       map[string]int
-	fingerprint []int
+	fingerprint uint64
 }
 
-func (msh *SimHash) CreateFingerPrint() {
+// Deli tekst na tokene od kojih se racuna otisak
+type Tokenizer interface {
+	Tokens(text string) []string
+}
 
-	stopwords := []string{"I", "me", "my", "myself", "we", "our", "ours", "ourselves", "you", "your", "yours", "yourself", "yourselves", "he", "him", "his", "himself", "she", "her", "hers", "herself", "it", "its", "itself", "they", "them", "their", "theirs", "themselves", "what", "which", "who", "whom", "this", "that", "these", "those", "am", "is", "are", "was", "were", "be", "been", "being", "have", "has", "had", "having", "do", "does", "did", "doing", "a", "an", "the", "and", "but", "if", "or", "because", "as", "until", "while", "of", "at", "by", "for", "with", "about", "against", "between", "into", "through", "during", "before", "after", "above", "below", "to", "from", "up", "down", "in", "out", "on", "off", "over", "under", "again", "further", "then", "once", "here", "there", "when", "where", "why", "how", "all", "any", "both", "each", "few", "more", "most", "other", "some", "such", "only", "own", "same", "so", "than", "too", "very", "s", "t", "can", "will", "just", "don", "should", "now"}
-	tokens := strings.Split(msh.text, " ")
-	for i, token := range tokens {
-		for _, s := range stopwords {
-			if s == token {
-				tokens[i] = "#nil"
-			}
-		}
+// Reci teksta malim slovima, sve sto nije slovo ili cifra razdvaja reci
+type WordTokenizer struct{}
+
+func (WordTokenizer) Tokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Svi nizovi od N uzastopnih karaktera teksta, otporni na greske u kucanju i spojene reci
+type CharNGramTokenizer struct {
+	N int
+}
+
+func (tokenizer CharNGramTokenizer) Tokens(text string) []string {
+	runes := []rune(strings.ToLower(text))
+	n := tokenizer.N
+	if n <= 0 {
+		n = 3
 	}
-	words := make(map[string]int)
-	for _, token := range tokens {
-		if token != "#nil" {
-			words[token] += 1
+	if len(runes) <= n {
+		if len(runes) == 0 {
+			return nil
 		}
+		return []string{string(runes)}
+	}
+	tokens := make([]string, 0, len(runes)-n+1)
+	for i := 0; i+n <= len(runes); i++ {
+		tokens = append(tokens, string(runes[i:i+n]))
 	}
+	return tokens
+}
 
-	msh.words = words
+// Nizovi od N uzastopnih reci (shingle-ovi), osetljivi i na redosled reci
+type ShingleTokenizer struct {
+	N int
+}
 
-	table := make([][]string, len(msh.words))
-	for m := range table {
-		table[m] = make([]string, 256)
+func (tokenizer ShingleTokenizer) Tokens(text string) []string {
+	words := WordTokenizer{}.Tokens(text)
+	n := tokenizer.N
+	if n <= 0 {
+		n = 2
 	}
-	i := 0
-	for word, _ := range msh.words {
-		str := ToBinary(GetMD5Hash(word))
-		for j := 0; j < len(str); j++ {
-			if string(str[j]) == "0" {
-				table[i][j] = "-1"
-			} else {
-				table[i][j] = "1"
-			}
+	if len(words) <= n {
+		if len(words) == 0 {
+			return nil
 		}
-		i++
+		return []string{strings.Join(words, " ")}
 	}
-
-	i = 0
-	fingerPrint := make([]int, 256)
-	for _, count := range msh.words {
-		for k := 0; k < len(table[i]); k++ {
-			n, _ := strconv.Atoi(table[i][k])
-			fingerPrint[k] += n * count
-		}
-		i++
+	tokens := make([]string, 0, len(words)-n+1)
+	for i := 0; i+n <= len(words); i++ {
+		tokens = append(tokens, strings.Join(words[i:i+n], " "))
 	}
+	return tokens
+}
 
-	for e, el := range fingerPrint {
-		if el <= 0 {
-			fingerPrint[e] = 0
-		} else {
-			fingerPrint[e] = 1
-		}
-	}
+/*
+Podesavanja racunanja otiska. Nil Tokenizer znaci WordTokenizer. Tokeni iz Stopwords se
+preskacu (WordTokenizer vraca mala slova, pa i stop reci treba da budu malim slovima). Svako
+pojavljivanje tokena nosi tezinu iz Weights, a tokeni kojih tamo nema tezinu 1.
+*/
+type Options struct {
+	Tokenizer Tokenizer
+	Stopwords map[string]bool
+	Weights   map[string]float64
+}
 
-	msh.fingerprint = fingerPrint
+var englishStopwords = []string{"i", "me", "my", "myself", "we", "our", "ours", "ourselves", "you", "your", "yours", "yourself", "yourselves", "he", "him", "his", "himself", "she", "her", "hers", "herself", "it", "its", "itself", "they", "them", "their", "theirs", "themselves", "what", "which", "who", "whom", "this", "that", "these", "those", "am", "is", "are", "was", "were", "be", "been", "being", "have", "has", "had", "having", "do", "does", "did", "doing", "a", "an", "the", "and", "but", "if", "or", "because", "as", "until", "while", "of", "at", "by", "for", "with", "about", "against", "between", "into", "through", "during", "before", "after", "above", "below", "to", "from", "up", "down", "in", "out", "on", "off", "over", "under", "again", "further", "then", "once", "here", "there", "when", "where", "why", "how", "all", "any", "both", "each", "few", "more", "most", "other", "some", "such", "only", "own", "same", "so", "than", "too", "very", "s", "t", "can", "will", "just", "don", "should", "now"}
 
+// Engleske stop reci, podrazumevane za NewSimHash
+func EnglishStopwords() map[string]bool {
+	stopwords := make(map[string]bool, len(englishStopwords))
+	for _, word := range englishStopwords {
+		stopwords[word] = true
+	}
+	return stopwords
 }
 
-func hammingDistance(msh1, msh2 SimHash) int {
+// Podrazumevana podesavanja: reci teksta bez engleskih stop reci, sve sa tezinom 1
+func DefaultOptions() Options {
+	return Options{Tokenizer: WordTokenizer{}, Stopwords: EnglishStopwords()}
+}
 
-	n := 0
-	for i, el := range msh1.fingerprint {
-		if (el == 0 && msh2.fingerprint[i] == 1) || (el == 1 && msh2.fingerprint[i] == 0) {
-			n += 1
+// 64-bitni FNV-1a hes tokena sa finalizerom iz MurmurHash3, da bi svi bitovi bili nezavisni
+func hashToken(token string) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(token))
+	x := hasher.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// Otisak teksta prema datim podesavanjima
+func Fingerprint(text string, options Options) uint64 {
+	tokenizer := options.Tokenizer
+	if tokenizer == nil {
+		tokenizer = WordTokenizer{}
+	}
+	var vector [64]float64
+	for _, token := range tokenizer.Tokens(text) {
+		if options.Stopwords[token] {
+			continue
+		}
+		weight := 1.0
+		if w, ok := options.Weights[token]; ok {
+			weight = w
+		}
+		hash := hashToken(token)
+		for i := 0; i < 64; i++ {
+			if hash&(1<<i) != 0 {
+				vector[i] += weight
+			} else {
+				vector[i] -= weight
+			}
 		}
 	}
+	var fingerprint uint64
+	for i, sum := range vector {
+		if sum > 0 {
+			fingerprint |= 1 << i
+		}
+	}
+	return fingerprint
+}
 
-	return n
+// Hamming rastojanje dva otiska, broj bitova u kojima se razlikuju
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
 
+// SimHash teksta sa podrazumevanim podesavanjima
+func NewSimHash(text string) *SimHash {
+	return NewSimHashWithOptions(text, DefaultOptions())
 }
 
-func GetMD5Hash(text string) string {
-	hash := md5.Sum([]byte(text))
-	return hex.EncodeToString(hash[:])
+func NewSimHashWithOptions(text string, options Options) *SimHash {
+	return &SimHash{text: text, fingerprint: Fingerprint(text, options)}
 }
 
-func ToBinary(s string) string {
-	res := ""
-	for _, c := range s {
-		res = fmt.Sprintf("%s%.8b", res, c)
-	}
-	return res
+func (msh *SimHash) Fingerprint() uint64 {
+	return msh.fingerprint
+}
+
+func (msh *SimHash) Text() string {
+	return msh.text
+}
+
+// Broj bitova u kojima se otisci razlikuju, manji broj znaci slicniji tekst
+func HammingDistance(msh1, msh2 *SimHash) int {
+	return Distance(msh1.fingerprint, msh2.fingerprint)
 }
 
+// Otisak (8 bajtova, little-endian) pa tekst od kog je napravljen
 func (msh *SimHash) SerializeSH() ([]byte, error) {
-	var buff bytes.Buffer
-	encoder := gob.NewEncoder(&buff)
-	err := encoder.Encode(msh)
-	if err != nil {
-		return nil, err
-	}
-	return buff.Bytes(), nil
+	data := binary.LittleEndian.AppendUint64(nil, msh.fingerprint)
+	return append(data, msh.text...), nil
 }
 
 func DeserializeSH(data []byte) (*SimHash, error) {
-	buff := bytes.NewBuffer(data)
-	decoder := gob.NewDecoder(buff)
-	sim := new(SimHash)
-
-	err := decoder.Decode(sim)
-	if err != nil {
-		return nil, err
+	if len(data) < 8 {
+		return nil, errors.New("simhash: invalid serialized fingerprint")
 	}
-
-	return sim, nil
+	return &SimHash{
+		text:        string(data[8:]),
+		fingerprint: binary.LittleEndian.Uint64(data[:8]),
+	}, nil
 }
 
 /*func main() {
-	msh1 := SimHash{"Branka Kovacevic", nil, nil}
-	msh1.CreateFingerPrint()
-	msh2 := SimHash{"Jovana Kovacevic", nil, nil}
-	msh2.CreateFingerPrint()
-	msh3 := SimHash{"Andjela Vostic", nil, nil}
-	msh3.CreateFingerPrint()
-
-	r := hammingDistance(msh1, msh2)
+	msh1 := NewSimHash("Branka Kovacevic")
+	msh2 := NewSimHash("Jovana Kovacevic")
+	msh3 := NewSimHash("Andjela Vostic")
+
+	r := HammingDistance(msh1, msh2)
 	fmt.Println("Hamming distance for similar sentences is ", r)
-	r = hammingDistance(msh1, msh3)
+	r = HammingDistance(msh1, msh3)
 	fmt.Println("Hamming distance for different sentences is ", r)
 
 }*/
@@ -0,0 +1,20 @@
+package sstable
+
+import (
+	"projekat_nasp/config"
+	"projekat_nasp/memTable"
+)
+
+// Upisuje sortirani sadrzaj memtabele u novu tabelu na nivou FLUSH_LEVEL, u formatu zadatom u konfiguraciji
+func FlushMemTable(data []memTable.MemTableEntry) {
+	level := FLUSH_LEVEL
+	if config.GlobalConfig.SStableAllInOne == false {
+		if config.GlobalConfig.SStableDegree != 0 {
+			CreateSStable_13(data, level, config.GlobalConfig.SStableDegree)
+		} else {
+			CreateSStable(data, level)
+		}
+	} else {
+		NewSSTable(&data, level)
+	}
+}
@@ -3,6 +3,7 @@ package sstable
 import (
 	"bufio"
 	"encoding/binary"
+	"io"
 	"log"
 	"math/rand"
 	"os"
@@ -204,6 +205,42 @@ func WriteSummary_13(keys []string, offsets []uint, filename string, step int) {
 	}
 }
 
+// Prvi i poslednji kljuc tabele, zapisani na pocetku summary fajla. Tabela sa jednim
+// kljucem ima samo jedan, koji je tada i prvi i poslednji.
+func summaryRange(filename string) (first, last string, ok bool) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", "", false
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	bytes := make([]byte, 8)
+	if _, err = io.ReadFull(reader, bytes); err != nil {
+		return "", "", false
+	}
+	count := binary.LittleEndian.Uint64(bytes)
+	if count == 0 {
+		return "", "", false
+	}
+
+	keys := make([]string, 2)
+	if count == 1 {
+		keys = keys[:1]
+	}
+	for i := range keys {
+		if _, err = io.ReadFull(reader, bytes); err != nil {
+			return "", "", false
+		}
+		keyBytes := make([]byte, binary.LittleEndian.Uint64(bytes))
+		if _, err = io.ReadFull(reader, keyBytes); err != nil {
+			return "", "", false
+		}
+		keys[i] = string(keyBytes)
+	}
+	return keys[0], keys[len(keys)-1], true
+}
+
 func FindSummary(key, filename string) (ok bool, offset int64) {
 	ok = false
 	offset = int64(8)
@@ -242,20 +279,23 @@ func FindSummary(key, filename string) (ok bool, offset int64) {
 		return false, 0
 	}
 
-	//end Key
-	bytes = make([]byte, 8)
-	_, err = reader.Read(bytes)
-	if err != nil {
-		panic(err)
-	}
-	keyLen = binary.LittleEndian.Uint64(bytes)
+	//end Key, tabela sa jednim kljucem ga nema
+	endKey := startKey
+	if fileLen > 1 {
+		bytes = make([]byte, 8)
+		_, err = reader.Read(bytes)
+		if err != nil {
+			panic(err)
+		}
+		keyLen = binary.LittleEndian.Uint64(bytes)
 
-	bytes = make([]byte, keyLen)
-	_, err = reader.Read(bytes)
-	if err != nil {
-		panic(err)
+		bytes = make([]byte, keyLen)
+		_, err = reader.Read(bytes)
+		if err != nil {
+			panic(err)
+		}
+		endKey = string(bytes[:])
 	}
-	endKey := string(bytes[:])
 
 	if key > endKey {
 		return false, 0
@@ -263,7 +303,7 @@ func FindSummary(key, filename string) (ok bool, offset int64) {
 
 	ok = true
 	var i uint64
-	for i = 0; i < fileLen-2; i++ {
+	for i = 2; i < fileLen; i++ {
 		good := false
 		bytes := make([]byte, 8)
 		_, err = reader.Read(bytes)
@@ -2,21 +2,33 @@ package sstable
 
 import (
 	"bufio"
-	"bytes"
 	"encoding/binary"
+	"fmt"
+	"io"
 	"os"
 	"path"
-	"projekat_nasp/bloom_filter"
 	"projekat_nasp/memTable"
 	"strings"
 )
 
+// Pretraga tabela za GET. Nivoi se obilaze od prvog, jer nizi nivo uvek ima noviju verziju,
+// i pretraga staje na prvom nivou koji sadrzi kljuc. Tabele ciji opseg kljuceva ne sadrzi kljuc se preskacu.
 func Main_search(keys []string) []memTable.MemTableEntry {
+	if len(keys) == 1 {
+		levels, byLevel := GroupByLevel(ListTables())
+		for _, level := range levels {
+			if found := searchLevel(byLevel[level], keys[0]); len(found) > 0 {
+				return found
+			}
+		}
+		return []memTable.MemTableEntry{}
+	}
+
 	files, _ := GetTables()
 	for _, file := range files {
 		if strings.HasPrefix(file, "file_") {
 			filePath := path.Join("data/sstable/", file)
-			retVal := FindByKey(keys, filePath, false)
+			retVal := FindByKey(keys, filePath, true)
 			if len(retVal) > 0 {
 				return retVal
 			}
@@ -25,11 +37,17 @@ func Main_search(keys []string) []memTable.MemTableEntry {
 	return []memTable.MemTableEntry{}
 }
 
+// Pretraga jedne tabele.
+// full = true i jedan kljuc -> tacna pretraga (koristi filter particije)
+// full = true i dva kljuca -> opseg [keys[0], keys[1]]
+// full = false -> svi kljucevi sa prefiksom keys[0]
 func FindByKey(keys []string, path string, full bool) []memTable.MemTableEntry {
 	f, err := os.OpenFile(path, os.O_RDONLY, 0600)
 	if err != nil {
 		panic(err)
 	}
+	defer f.Close()
+
 	var key string
 	var keySec string
 	if len(keys) == 1 {
@@ -40,455 +58,148 @@ func FindByKey(keys []string, path string, full bool) []memTable.MemTableEntry {
 		keySec = keys[1]
 	}
 
-	maybeInFile := true
-	if full && len(keys) == 1 {
-		maybeInFile = checkBloomFilter(f, key)
-	}
-	defer f.Close()
-
-	if maybeInFile {
-		result := checkSummary(f, key, full, keySec)
-		if len(result) != 0 {
-			return result
-		} else {
-			return []memTable.MemTableEntry{}
-		}
-	} else {
-		return []memTable.MemTableEntry{}
-	}
-
-}
-
-func checkBloomFilter(file *os.File, key string) bool {
-	var bF bloom_filter.BloomFilterUnique
-	var bfpos int64
-	var bfDS int64
-	file.Seek(16, 0)
-	bufferedReader := bufio.NewReader(file)
-	byteSlice := make([]byte, M_SIZE)
-	_, err := bufferedReader.Read(byteSlice)
-	if err != nil {
-		panic(err)
-	}
-	binary.Read(bytes.NewReader(byteSlice), binary.LittleEndian, &bfpos)
-	file.Seek(24, 0)
-	bufferedReader = bufio.NewReader(file)
-	byteSlice = make([]byte, M_SIZE)
-	_, err = bufferedReader.Read(byteSlice)
-	if err != nil {
-		panic(err)
-	}
-	binary.Read(bytes.NewReader(byteSlice), binary.LittleEndian, &bfDS)
-	file.Seek(bfpos, 0)
-	bufferedReader = bufio.NewReader(file)
-	byteSlice = make([]byte, M_SIZE)
-	_, err = bufferedReader.Read(byteSlice)
-	if err != nil {
-		panic(err)
-	}
-	var bfM int64
-	binary.Read(bytes.NewReader(byteSlice), binary.LittleEndian, &bfM)
-	bF.M = uint(bfM)
-
-	byteSlice = make([]byte, bfDS)
-	_, err = bufferedReader.Read(byteSlice)
-	if err != nil {
-		panic(err)
-	}
-	bF.Data = byteSlice
-
-	var forRead int64
-	for {
-		forRead = 0
-		byteSlice = make([]byte, K_SIZE)
-		_, err = bufferedReader.Read(byteSlice)
-		if err != nil {
-			break
-		}
-		binary.Read(bytes.NewReader(byteSlice), binary.LittleEndian, &forRead)
-		byteSlice = make([]byte, forRead)
-		_, err = bufferedReader.Read(byteSlice)
-		if err != nil {
-			break
-		}
-		bF.HashFunctions = append(bF.HashFunctions, bloom_filter.HashWithSeed{Seed: byteSlice})
-
-	}
-	if bF.Read([]byte(key)) {
-		return true
-	} else {
-		return false
-	}
-}
-
-func checkSummary(file *os.File, key string, full bool, keySec string) []memTable.MemTableEntry {
-	file.Seek(0, 0)
-	bufferedReader := bufio.NewReader(file)
-	dsb := make([]byte, K_SIZE)
-	isb := make([]byte, K_SIZE)
-	bfb := make([]byte, K_SIZE)
-	_, err := bufferedReader.Read(dsb)
-	if err != nil {
-		return []memTable.MemTableEntry{}
-	}
-	_, err = bufferedReader.Read(isb)
-	if err != nil {
-		return []memTable.MemTableEntry{}
-	}
-	_, err = bufferedReader.Read(bfb)
-	if err != nil {
-		return []memTable.MemTableEntry{}
-	}
-	var ds int64
-	var is int64
-	var bf int64
-	binary.Read(bytes.NewReader(dsb), binary.LittleEndian, &ds)
-	binary.Read(bytes.NewReader(isb), binary.LittleEndian, &is)
-	binary.Read(bytes.NewReader(bfb), binary.LittleEndian, &bf)
-	sumPos := ds + is - HEADER_SIZE
-
-	file.Seek(int64(sumPos), 0)
-	bufferedReader = bufio.NewReader(file)
-	var keyLen int64
-	keyLenB := make([]byte, K_SIZE)
-	_, err = bufferedReader.Read(keyLenB)
+	header, err := readHeader(f)
 	if err != nil {
 		return []memTable.MemTableEntry{}
 	}
-	binary.Read(bytes.NewReader(keyLenB), binary.LittleEndian, &keyLen)
-	otherLenB := make([]byte, keyLen+VALUE_SIZE_LEN)
-	_, err = bufferedReader.Read(otherLenB)
-	if err != nil {
-		return []memTable.MemTableEntry{}
+	policy := ""
+	compression := ""
+	var ingestTimestamp uint64
+	props, err := readPropertiesBlock(f, header)
+	if err == nil {
+		if !mayContainKeys(props, key, keySec, full) {
+			return []memTable.MemTableEntry{}
+		}
+		policy = props.FilterPolicy
+		compression = props.Compression
+		ingestTimestamp = props.IngestTimestamp
 	}
-	key1 := string(otherLenB[0:keyLen])
-	if full && key < key1 && keySec == "" {
+	partitions, err := readTopLevelIndex(f, header)
+	if err != nil || len(partitions) == 0 {
 		return []memTable.MemTableEntry{}
 	}
-	var index1 int64
-	binary.Read(bytes.NewReader(otherLenB[keyLen:]), binary.LittleEndian, &index1)
-	sumPos += K_SIZE + keyLen + VALUE_SIZE_LEN
 
-	key2 := key1
-	index2 := index1
-	for sumPos < bf {
-		var keyLen int64
-		keyLenB := make([]byte, K_SIZE)
-		_, err = bufferedReader.Read(keyLenB)
-		if err != nil {
-			return []memTable.MemTableEntry{}
-		}
-		binary.Read(bytes.NewReader(keyLenB), binary.LittleEndian, &keyLen)
-		otherLenB := make([]byte, keyLen+VALUE_SIZE_LEN)
-		_, err = bufferedReader.Read(otherLenB)
-		if err != nil {
+	if full && keySec == "" {
+		p := findPartition(partitions, key)
+		if p < 0 || !checkFilterPartition(f, partitions[p], policy, key) {
 			return []memTable.MemTableEntry{}
 		}
-		key2 = string(otherLenB[0:keyLen])
-		binary.Read(bytes.NewReader(otherLenB[keyLen:]), binary.LittleEndian, &index2)
-		if full {
-			if key >= key1 && key2 > key && keySec == "" {
-				return checkIndexZone(key, index1, index2, file, ds, is, full, keySec)
-			}
-			if len(key) <= len(key1) {
-				if key <= key1[:len(key)] && keySec != "" {
-					return checkIndexZone(key, index1, index2, file, ds, is, full, keySec)
-				}
-				if key >= key1[:len(key)] && keySec != "" && key < key2 {
-					return checkIndexZone(key, index1, index2, file, ds, is, full, keySec)
-				}
-			} else {
-				if key <= key1 && keySec != "" {
-					return checkIndexZone(key, index1, index2, file, ds, is, full, keySec)
-				}
-				if key >= key1 && keySec != "" && key < key2 {
-					return checkIndexZone(key, index1, index2, file, ds, is, full, keySec)
-				}
-			}
-		} else {
-			if len(key) <= len(key1) {
-				if key >= key1[:len(key)] && key2 > key {
-					return checkIndexZone(key, index1, index2, file, ds, is, full, keySec)
-				}
-			} else {
-				if key >= key1 && key2 > key {
-					return checkIndexZone(key, index1, index2, file, ds, is, full, keySec)
-				}
-			}
-		}
-		sumPos += K_SIZE + keyLen + VALUE_SIZE_LEN
-		key1 = key2
-		index1 = index2
 	}
-	return checkIndexZone(key, index2, ds+is-HEADER_SIZE, file, ds, is, full, keySec)
-}
 
-func checkIndexZone(key string, iPos int64, maxPos int64, file *os.File, ds int64, is int64, full bool, keySec string) []memTable.MemTableEntry {
-	file.Seek(int64(iPos), 0)
-	bufferedReader := bufio.NewReader(file)
-	var keyLen int64
-	keyLenB := make([]byte, K_SIZE)
-	_, err := bufferedReader.Read(keyLenB)
+	start, err := findBlock(f, partitions, key)
 	if err != nil {
 		return []memTable.MemTableEntry{}
 	}
-	binary.Read(bytes.NewReader(keyLenB), binary.LittleEndian, &keyLen)
-	otherLenB := make([]byte, keyLen+VALUE_SIZE_LEN)
-	_, err = bufferedReader.Read(otherLenB)
-	if err != nil {
-		return []memTable.MemTableEntry{}
-	}
-	key1 := string(otherLenB[0:keyLen])
-	if full && key < key1 && keySec == "" {
-		return []memTable.MemTableEntry{}
-	}
-	var index1 int64
-	binary.Read(bytes.NewReader(otherLenB[keyLen:]), binary.LittleEndian, &index1)
-	iPos += K_SIZE + keyLen + VALUE_SIZE_LEN
 
-	key2 := key1
-	index2 := index1
-	for iPos < maxPos {
-		var keyLen int64
-		keyLenB := make([]byte, K_SIZE)
-		_, err = bufferedReader.Read(keyLenB)
-		if err != nil {
-			return []memTable.MemTableEntry{}
-		}
-		binary.Read(bytes.NewReader(keyLenB), binary.LittleEndian, &keyLen)
-		otherLenB := make([]byte, keyLen+VALUE_SIZE_LEN)
-		_, err = bufferedReader.Read(otherLenB)
-		if err != nil {
-			return []memTable.MemTableEntry{}
-		}
-		key2 = string(otherLenB[0:keyLen])
-		binary.Read(bytes.NewReader(otherLenB[keyLen:]), binary.LittleEndian, &index2)
-		if full {
-			if key >= key1 && key2 > key && keySec == "" {
-				return checkDataZone(key, index1, index2, file, ds, full, keySec)
+	var values []memTable.MemTableEntry
+	scanData(f, start, header.dataEnd, compression, func(entry memTable.MemTableEntry) bool {
+		newKey := entry.GetKey()
+		// kao i u Iterator-u, zapisi ucitane tabele imaju timestamp ucitavanja
+		if ingestTimestamp != 0 {
+			entry = memTable.FillWithParametersEntry(newKey, entry.GetValue(), ingestTimestamp, entry.GetTombstone())
+		}
+		switch {
+		case full && keySec == "":
+			if newKey == key {
+				values = append(values, entry)
 			}
-			if len(key) <= len(key1) {
-				if key <= key1[:len(key)] && keySec != "" {
-					return checkDataZone(key, index1, index2, file, ds, full, keySec)
-				}
-				if key >= key1[:len(key)] && keySec != "" && key < key2 {
-					return checkDataZone(key, index1, index2, file, ds, full, keySec)
-				}
-			} else {
-				if key <= key1 && keySec != "" {
-					return checkDataZone(key, index1, index2, file, ds, full, keySec)
-				}
-				if key >= key1 && keySec != "" && key < key2 {
-					return checkDataZone(key, index1, index2, file, ds, full, keySec)
-				}
+			return newKey < key
+		case keySec != "":
+			if newKey > keySec {
+				return false
 			}
-		} else {
-			if len(key) <= len(key1) {
-				if key >= key1[:len(key)] && key2 > key {
-					return checkDataZone(key, index1, index2, file, ds, full, keySec)
-				}
-			} else {
-				if key >= key1 && key2 > key {
-					return checkDataZone(key, index1, index2, file, ds, full, keySec)
-				}
+			if newKey >= key {
+				values = append(values, entry)
 			}
+			return true
+		default:
+			if strings.HasPrefix(newKey, key) {
+				values = append(values, entry)
+				return true
+			}
+			return newKey < key
 		}
+	})
+	return values
+}
 
-		iPos += K_SIZE + keyLen + VALUE_SIZE_LEN
-		key1 = key2
-		index1 = index2
+// Na osnovu najmanjeg i najveceg kljuca tabele proverava da li tabela moze da sadrzi trazene kljuceve
+func mayContainKeys(props *TableProperties, key, keySec string, full bool) bool {
+	switch {
+	case full && keySec == "":
+		return props.MayContain(key)
+	case keySec != "":
+		return props.Overlaps(key, keySec)
+	default:
+		return props.NumEntries > 0 &&
+			(props.LargestKey >= key || strings.HasPrefix(props.LargestKey, key)) &&
+			(props.SmallestKey <= key || strings.HasPrefix(props.SmallestKey, key))
 	}
+}
 
-	if full {
-		if len(key) <= len(key2) {
-			if key >= key2[:len(key)] && maxPos == ds+is-HEADER_SIZE {
-				return checkDataZone(key, index2, ds, file, ds, full, keySec)
-			} else {
-				var keyLen int64
-				keyLenB := make([]byte, K_SIZE)
-				_, err = bufferedReader.Read(keyLenB)
-				if err != nil {
-					return []memTable.MemTableEntry{}
-				}
-				binary.Read(bytes.NewReader(keyLenB), binary.LittleEndian, &keyLen)
-				otherLenB := make([]byte, keyLen+VALUE_SIZE_LEN)
-				_, err = bufferedReader.Read(otherLenB)
-				if err != nil {
-					return []memTable.MemTableEntry{}
-				}
-				binary.Read(bytes.NewReader(otherLenB[keyLen:]), binary.LittleEndian, &index2)
-				return checkDataZone(key, index1, index2, file, ds, full, keySec)
-			}
-		} else {
-			if key >= key2 && maxPos == ds+is-HEADER_SIZE {
-				return checkDataZone(key, index2, ds, file, ds, full, keySec)
-			} else {
-				var keyLen int64
-				keyLenB := make([]byte, K_SIZE)
-				_, err = bufferedReader.Read(keyLenB)
-				if err != nil {
-					return []memTable.MemTableEntry{}
-				}
-				binary.Read(bytes.NewReader(keyLenB), binary.LittleEndian, &keyLen)
-				otherLenB := make([]byte, keyLen+VALUE_SIZE_LEN)
-				_, err = bufferedReader.Read(otherLenB)
-				if err != nil {
-					return []memTable.MemTableEntry{}
-				}
-				binary.Read(bytes.NewReader(otherLenB[keyLen:]), binary.LittleEndian, &index2)
-				return checkDataZone(key, index1, index2, file, ds, full, keySec)
-			}
-		}
-
-	} else {
-		if key >= key2 && maxPos == ds+is-HEADER_SIZE {
-			return checkDataZone(key, index2, ds, file, ds, full, keySec)
-		} else {
-			var keyLen int64
-			keyLenB := make([]byte, K_SIZE)
-			_, err = bufferedReader.Read(keyLenB)
-			if err != nil {
-				return []memTable.MemTableEntry{}
-			}
-			binary.Read(bytes.NewReader(keyLenB), binary.LittleEndian, &keyLen)
-			otherLenB := make([]byte, keyLen+VALUE_SIZE_LEN)
-			_, err = bufferedReader.Read(otherLenB)
+// Ispisuje properties svih tabela
+func PrintProperties() {
+	files, _ := GetTables()
+	for _, file := range files {
+		if strings.HasPrefix(file, "file_") {
+			props, err := ReadProperties(path.Join("data/sstable/", file))
 			if err != nil {
-				return []memTable.MemTableEntry{}
+				fmt.Println(file, err)
+				continue
 			}
-
-			binary.Read(bytes.NewReader(otherLenB[keyLen:]), binary.LittleEndian, &index2)
-			return checkDataZone(key, index1, index2, file, ds, full, keySec)
+			fmt.Println(file)
+			props.Print()
 		}
 	}
 }
 
-func checkDataZone(key string, iPos int64, maxPos int64, file *os.File, ds int64, full bool, keySec string) []memTable.MemTableEntry {
-	file.Seek(int64(iPos), 0)
-	var keyLen int64
-	var valueLen int64
-	var newKey string
-	var timestamp int64
-	var tombstone byte
-	var values []memTable.MemTableEntry
-	bufferedReader := bufio.NewReader(file)
-	keyLenB := make([]byte, KEY_SIZE_LEN)
-	_, err := bufferedReader.Read(keyLenB)
-	if err != nil {
-		return []memTable.MemTableEntry{}
+// Cita jedan zapis data zone: KS(8), VS(8), TIME(8), TB(1), K(...), V(...). Za tabele sa
+// kompresijom VARINT_COMPRESSION velicina vrednosti je uvarint od 1 do 10 bajtova.
+func readRecord(reader *bufio.Reader, compression string) (memTable.MemTableEntry, int64, error) {
+	if compression == VARINT_COMPRESSION {
+		return readRecordVarInt(reader)
 	}
-	valueLenB := make([]byte, VALUE_SIZE_LEN)
-	_, err = bufferedReader.Read(valueLenB)
+	header := make([]byte, KEY_VALUE_START)
+	_, err := io.ReadFull(reader, header)
 	if err != nil {
-		return []memTable.MemTableEntry{}
+		return memTable.MemTableEntry{}, 0, err
 	}
-	binary.Read(bytes.NewReader(keyLenB), binary.LittleEndian, &keyLen)
-	binary.Read(bytes.NewReader(valueLenB), binary.LittleEndian, &valueLen)
+	keySize := binary.LittleEndian.Uint64(header[0:KEY_SIZE_LEN])
+	valueSize := binary.LittleEndian.Uint64(header[KEY_SIZE_LEN : KEY_SIZE_LEN+VALUE_SIZE_LEN])
+	timestamp := binary.LittleEndian.Uint64(header[KEY_SIZE_LEN+VALUE_SIZE_LEN : KEY_SIZE_LEN+VALUE_SIZE_LEN+TIMESTAMP_LEN])
+	tombstone := header[KEY_SIZE_LEN+VALUE_SIZE_LEN+TIMESTAMP_LEN]
 
-	otherB := make([]byte, keyLen+valueLen+TIMESTAMP_LEN+TOMBSTONE_LEN)
-	_, err = bufferedReader.Read(otherB)
+	keyValue := make([]byte, keySize+valueSize)
+	_, err = io.ReadFull(reader, keyValue)
 	if err != nil {
-		return []memTable.MemTableEntry{}
+		return memTable.MemTableEntry{}, 0, err
 	}
-	newKey = string(otherB[TIMESTAMP_LEN+TOMBSTONE_LEN : TIMESTAMP_LEN+TOMBSTONE_LEN+keyLen])
-	if full {
-		if key == newKey && keySec == "" {
-			value := otherB[TIMESTAMP_LEN+TOMBSTONE_LEN+keyLen:]
-			binary.Read(bytes.NewReader(otherB[:TIMESTAMP_LEN]), binary.LittleEndian, &timestamp)
-			binary.Read(bytes.NewReader(otherB[TIMESTAMP_LEN:TIMESTAMP_LEN+TOMBSTONE_LEN]), binary.LittleEndian, &tombstone)
-
-			values = append(values, memTable.NewMemTableEntry(newKey, value, tombstone, uint64(timestamp)))
-
-			return values
-		}
-		if keySec != "" && key <= newKey && key <= keySec {
-			value := otherB[TIMESTAMP_LEN+TOMBSTONE_LEN+keyLen:]
-			binary.Read(bytes.NewReader(otherB[:TIMESTAMP_LEN]), binary.LittleEndian, &timestamp)
-			binary.Read(bytes.NewReader(otherB[TIMESTAMP_LEN:TIMESTAMP_LEN+TOMBSTONE_LEN]), binary.LittleEndian, &tombstone)
-
-			values = append(values, memTable.NewMemTableEntry(newKey, value, tombstone, uint64(timestamp)))
 
-		}
-	} else {
-		if len(key) <= len(newKey) {
-			if key == newKey[:len(key)] {
-				value := otherB[TIMESTAMP_LEN+TOMBSTONE_LEN+keyLen:]
-				binary.Read(bytes.NewReader(otherB[:TIMESTAMP_LEN]), binary.LittleEndian, &timestamp)
-				binary.Read(bytes.NewReader(otherB[TIMESTAMP_LEN:TIMESTAMP_LEN+TOMBSTONE_LEN]), binary.LittleEndian, &tombstone)
-
-				values = append(values, memTable.NewMemTableEntry(newKey, value, tombstone, uint64(timestamp)))
+	readBytes := int64(KEY_VALUE_START) + int64(keySize+valueSize)
+	return memTable.FillWithParametersEntry(string(keyValue[:keySize]), keyValue[keySize:], timestamp, tombstone), readBytes, nil
+}
 
-			}
-		}
+// Zapis koji je upisao encodeRecordVarInt: KS(8), VS(1-10), TIME(8), TB(1), K(...), V(...)
+func readRecordVarInt(reader *bufio.Reader) (memTable.MemTableEntry, int64, error) {
+	keySizeBytes := make([]byte, KEY_SIZE_LEN)
+	_, err := io.ReadFull(reader, keySizeBytes)
+	if err != nil {
+		return memTable.MemTableEntry{}, 0, err
 	}
-	iPos += keyLen + valueLen + KEY_SIZE_LEN + VALUE_SIZE_LEN + TIMESTAMP_LEN + TOMBSTONE_LEN
-	if iPos >= ds {
-		return values
+	keySize := binary.LittleEndian.Uint64(keySizeBytes)
+	valueSize, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return memTable.MemTableEntry{}, 0, io.ErrUnexpectedEOF
 	}
-
-	for iPos < maxPos {
-		file.Seek(iPos, 0)
-		bufferedReader = bufio.NewReader(file)
-		keyLenB = make([]byte, KEY_SIZE_LEN)
-		_, err = bufferedReader.Read(keyLenB)
-		if err != nil {
-			return []memTable.MemTableEntry{}
-		}
-		valueLenB = make([]byte, VALUE_SIZE_LEN)
-		_, err = bufferedReader.Read(valueLenB)
-		if err != nil {
-			return []memTable.MemTableEntry{}
-		}
-		binary.Read(bytes.NewReader(keyLenB), binary.LittleEndian, &keyLen)
-		binary.Read(bytes.NewReader(valueLenB), binary.LittleEndian, &valueLen)
-
-		otherB = make([]byte, keyLen+valueLen+TIMESTAMP_LEN+TOMBSTONE_LEN)
-		_, err = bufferedReader.Read(otherB)
-		if err != nil {
-			return []memTable.MemTableEntry{}
-		}
-		newKey = string(otherB[TIMESTAMP_LEN+TOMBSTONE_LEN : TIMESTAMP_LEN+TOMBSTONE_LEN+keyLen])
-		if (newKey > keySec || iPos > ds) && keySec != "" {
-			return values
-		}
-		if full {
-			if key == newKey && keySec == "" {
-				value := otherB[TIMESTAMP_LEN+TOMBSTONE_LEN+keyLen:]
-				binary.Read(bytes.NewReader(otherB[:TIMESTAMP_LEN]), binary.LittleEndian, &timestamp)
-				binary.Read(bytes.NewReader(otherB[TIMESTAMP_LEN:TIMESTAMP_LEN+TOMBSTONE_LEN]), binary.LittleEndian, &tombstone)
-
-				values = append(values, memTable.NewMemTableEntry(newKey, value, tombstone, uint64(timestamp)))
-
-				return values
-			}
-			if keySec != "" && key <= newKey && key <= keySec {
-				value := otherB[TIMESTAMP_LEN+TOMBSTONE_LEN+keyLen:]
-				binary.Read(bytes.NewReader(otherB[:TIMESTAMP_LEN]), binary.LittleEndian, &timestamp)
-				binary.Read(bytes.NewReader(otherB[TIMESTAMP_LEN:TIMESTAMP_LEN+TOMBSTONE_LEN]), binary.LittleEndian, &tombstone)
-
-				values = append(values, memTable.NewMemTableEntry(newKey, value, tombstone, uint64(timestamp)))
-
-			}
-
-		} else {
-			if len(key) <= len(newKey) {
-				if key < newKey[:len(key)] {
-					return values
-				}
-				if key == newKey[:len(key)] {
-					value := otherB[TIMESTAMP_LEN+TOMBSTONE_LEN+keyLen:]
-					binary.Read(bytes.NewReader(otherB[:TIMESTAMP_LEN]), binary.LittleEndian, &timestamp)
-					binary.Read(bytes.NewReader(otherB[TIMESTAMP_LEN:TIMESTAMP_LEN+TOMBSTONE_LEN]), binary.LittleEndian, &tombstone)
-					values = append(values, memTable.NewMemTableEntry(newKey, value, tombstone, uint64(timestamp)))
-				}
-			}
-		}
-		maxPos += keyLen + valueLen + KEY_SIZE_LEN + VALUE_SIZE_LEN + TIMESTAMP_LEN + TOMBSTONE_LEN
-
-		iPos += keyLen + valueLen + KEY_SIZE_LEN + VALUE_SIZE_LEN + TIMESTAMP_LEN + TOMBSTONE_LEN
-		if iPos >= ds {
-			return values
-		}
+	rest := make([]byte, TIMESTAMP_LEN+TOMBSTONE_LEN+keySize+valueSize)
+	_, err = io.ReadFull(reader, rest)
+	if err != nil {
+		return memTable.MemTableEntry{}, 0, err
 	}
-	return values
+	timestamp := binary.LittleEndian.Uint64(rest[0:TIMESTAMP_LEN])
+	tombstone := rest[TIMESTAMP_LEN]
+	keyValue := rest[TIMESTAMP_LEN+TOMBSTONE_LEN:]
+
+	readBytes := int64(KEY_SIZE_LEN+len(encodeVarInt(valueSize))) + int64(len(rest))
+	return memTable.FillWithParametersEntry(string(keyValue[:keySize]), keyValue[keySize:], timestamp, tombstone), readBytes, nil
 }
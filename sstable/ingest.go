@@ -0,0 +1,195 @@
+package sstable
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	merkletree "projekat_nasp/merkle_tree"
+)
+
+/*
+Tabela napravljena van baze (npr. Writer-om iz NewWriterInDir) koja se ucitava sa IngestFiles.
+Ucitavanje ide u tri koraka:
+  - ValidateExternalTable proverava format i redosled kljuceva
+  - Prepare kopira tabelu u data/sstable pod privremenim imenom i upisuje joj timestamp
+  - Commit je preimenuje u file_<unixTime>_<level>.db, tek tada je tabela vidljiva
+*/
+type ExternalTable struct {
+	Source     string
+	Properties *TableProperties
+	Path       string // konacna putanja u data/sstable, poznata posle Prepare
+	tmpPath    string
+	unixTime   int64
+	hashes     [][]byte
+	committed  bool
+}
+
+// Proverava da li je fajl ispravna tabela: zaglavlje, top-level index, properties
+// i da su kljucevi strogo rastuci i u skladu sa properties blokom
+func ValidateExternalTable(path string) (*ExternalTable, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	header, err := readHeader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if header.propertiesOffset+header.propertiesSize != uint64(info.Size()) {
+		return nil, fmt.Errorf("%s: sstable: file size does not match header", path)
+	}
+	_, err = readTopLevelIndex(file, header)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	props, err := readPropertiesBlock(file, header)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if props.Compression != "none" {
+		return nil, fmt.Errorf("%s: sstable: compression %q can not be ingested", path, props.Compression)
+	}
+	if props.NumEntries == 0 {
+		return nil, fmt.Errorf("%s: sstable: table is empty", path)
+	}
+
+	it, err := NewIterator(path)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	table := &ExternalTable{Source: path, Properties: props}
+	var count uint64
+	var lastKey string
+	for it.Next() {
+		entry := it.Entry()
+		key := entry.GetKey()
+		if count > 0 && key <= lastKey {
+			return nil, fmt.Errorf("%s: sstable: key %s is not greater than previous key %s", path, key, lastKey)
+		}
+		if count == 0 && key != props.SmallestKey {
+			return nil, fmt.Errorf("%s: sstable: first key does not match properties", path)
+		}
+		table.hashes = append(table.hashes, merkletree.Hash(append([]byte(key), entry.GetValue()...)))
+		lastKey = key
+		count++
+	}
+	if it.Err() != nil {
+		return nil, fmt.Errorf("%s: %w", path, it.Err())
+	}
+	if count != props.NumEntries || lastKey != props.LargestKey {
+		return nil, fmt.Errorf("%s: sstable: records do not match properties", path)
+	}
+	return table, nil
+}
+
+// Kopira tabelu u data/sstable pod privremenim imenom, dodeljuje joj redni broj i upisuje
+// timestamp koji vazi za sve njene zapise. Tabela jos nije vidljiva pretrazi.
+func (table *ExternalTable) Prepare(level int, timestamp uint64) error {
+	table.unixTime = nextUnixTime()
+	table.Path = filepath.Join("data/sstable", "file_"+fmt.Sprint(table.unixTime)+"_"+fmt.Sprint(level)+".db")
+	table.tmpPath = filepath.Join("data/sstable", "ingest_"+fmt.Sprint(table.unixTime)+".tmp")
+
+	err := copyFile(table.Source, table.tmpPath)
+	if err != nil {
+		table.Rollback()
+		return err
+	}
+
+	table.Properties.IngestTimestamp = timestamp
+	err = rewriteProperties(table.tmpPath, table.Properties)
+	if err != nil {
+		table.Rollback()
+		return err
+	}
+	merkletree.BuildMerkleTreeFromHashes(table.hashes, MerklePath("data/sstable", table.unixTime))
+	return nil
+}
+
+// Preimenovanjem tabela postaje deo baze
+func (table *ExternalTable) Commit() error {
+	err := os.Rename(table.tmpPath, table.Path)
+	if err != nil {
+		return err
+	}
+	table.committed = true
+	return nil
+}
+
+// Brise sve sto je Prepare/Commit napravio
+func (table *ExternalTable) Rollback() {
+	if table.tmpPath == "" {
+		return
+	}
+	os.Remove(table.tmpPath)
+	if table.committed {
+		os.Remove(table.Path)
+		ForgetTable(table.Path)
+		table.committed = false
+	}
+	os.Remove(MerklePath("data/sstable", table.unixTime))
+}
+
+func copyFile(source, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	err = out.Sync()
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Menja properties blok na kraju tabele i njegovu velicinu u zaglavlju
+func rewriteProperties(path string, props *TableProperties) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header, err := readHeader(file)
+	if err != nil {
+		return err
+	}
+	propertiesBytes := props.Encode()
+	_, err = file.WriteAt(propertiesBytes, int64(header.propertiesOffset))
+	if err != nil {
+		return err
+	}
+	// blok iste duzine (npr. kada se menja samo nivo) se prepisuje na mestu, bez skracivanja fajla
+	if uint64(len(propertiesBytes)) < header.propertiesSize {
+		err = file.Truncate(int64(header.propertiesOffset) + int64(len(propertiesBytes)))
+		if err != nil {
+			return err
+		}
+	}
+	// propertiesSize je poslednje polje zaglavlja
+	_, err = file.WriteAt(uint64Bytes(uint64(len(propertiesBytes))), HEADER_SIZE-8)
+	if err != nil {
+		return err
+	}
+	return file.Sync()
+}
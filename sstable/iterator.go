@@ -0,0 +1,83 @@
+package sstable
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"projekat_nasp/memTable"
+)
+
+// Iterator redom cita zapise data zone jedne tabele.
+// Za tabele ucitane sa IngestFiles svaki zapis dobija timestamp iz properties bloka.
+type Iterator struct {
+	Path            string
+	file            *os.File
+	reader          *bufio.Reader
+	pos             uint64
+	dataEnd         uint64
+	compression     string
+	ingestTimestamp uint64
+	entry           memTable.MemTableEntry
+	err             error
+}
+
+func NewIterator(path string) (*Iterator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	header, err := readHeader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	props, err := readPropertiesBlock(file, header)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	_, err = file.Seek(HEADER_SIZE, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Iterator{
+		Path:            path,
+		file:            file,
+		reader:          bufio.NewReader(file),
+		pos:             HEADER_SIZE,
+		dataEnd:         header.dataEnd,
+		compression:     props.Compression,
+		ingestTimestamp: props.IngestTimestamp,
+	}, nil
+}
+
+// Pomera iterator na sledeci zapis, vraca false kada zapisa vise nema ili je doslo do greske
+func (it *Iterator) Next() bool {
+	if it.err != nil || it.pos >= it.dataEnd {
+		return false
+	}
+	entry, n, err := readRecord(it.reader, it.compression)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.pos += uint64(n)
+	if it.ingestTimestamp != 0 {
+		entry = memTable.FillWithParametersEntry(entry.GetKey(), entry.GetValue(), it.ingestTimestamp, entry.GetTombstone())
+	}
+	it.entry = entry
+	return true
+}
+
+func (it *Iterator) Entry() memTable.MemTableEntry {
+	return it.entry
+}
+
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+func (it *Iterator) Close() error {
+	return it.file.Close()
+}
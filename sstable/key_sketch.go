@@ -0,0 +1,211 @@
+package sstable
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"projekat_nasp/config"
+	"projekat_nasp/hyperloglog"
+	"projekat_nasp/memTable"
+)
+
+// Preciznost HyperLogLog++ skica kljuceva u properties bloku, greska procene je oko 3%
+const KEY_SKETCH_PRECISION = 10
+
+/*
+HyperLogLog++ razlicitih korisnickih kljuceva jednog dela tabele: writer posle svakih
+KeySketchChunk kljuceva zapocinje novu skicu i pamti njen prvi i poslednji kljuc. Zbog toga se
+broj kljuceva u opsegu moze proceniti spajanjem skica, a samo delovi tabele na granicama opsega
+moraju da se procitaju.
+*/
+type KeySketch struct {
+	FirstKey string
+	LastKey  string
+	Count    uint64 // broj zapisa u delu tabele
+	Sketch   *hyperloglog.HLLPlus
+}
+
+func keySketchChunk() uint64 {
+	chunk := config.GlobalConfig.KeySketchChunk
+	if chunk <= 0 {
+		chunk = config.KEY_SKETCH_CHUNK
+	}
+	return uint64(chunk)
+}
+
+func (props *TableProperties) addToKeySketch(key string) {
+	last := len(props.KeySketches) - 1
+	if last < 0 || props.KeySketches[last].Count >= keySketchChunk() {
+		props.KeySketches = append(props.KeySketches, KeySketch{
+			FirstKey: key,
+			Sketch:   hyperloglog.NewHLLPlus(KEY_SKETCH_PRECISION),
+		})
+		last++
+	}
+	chunk := &props.KeySketches[last]
+	chunk.LastKey = key
+	chunk.Count++
+	chunk.Sketch.Add(key)
+}
+
+// Procena broja razlicitih korisnickih kljuceva u celoj tabeli
+func (props *TableProperties) DistinctKeys() float64 {
+	sketch := hyperloglog.NewHLLPlus(KEY_SKETCH_PRECISION)
+	for _, chunk := range props.KeySketches {
+		sketch.Merge(chunk.Sketch)
+	}
+	return sketch.Count()
+}
+
+// broj skica | (prvi kljuc | poslednji kljuc | broj zapisa | skica), duzine i brojevi kao uvarint
+func encodeKeySketches(sketches []KeySketch) []byte {
+	data := binary.AppendUvarint(nil, uint64(len(sketches)))
+	for _, chunk := range sketches {
+		sketch := chunk.Sketch.Serialize()
+		data = binary.AppendUvarint(data, uint64(len(chunk.FirstKey)))
+		data = append(data, chunk.FirstKey...)
+		data = binary.AppendUvarint(data, uint64(len(chunk.LastKey)))
+		data = append(data, chunk.LastKey...)
+		data = binary.AppendUvarint(data, chunk.Count)
+		data = binary.AppendUvarint(data, uint64(len(sketch)))
+		data = append(data, sketch...)
+	}
+	return data
+}
+
+func decodeKeySketches(data []byte) ([]KeySketch, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	corrupted := errors.New("sstable: key sketches corrupted")
+	next := func() ([]byte, bool) {
+		length, n := binary.Uvarint(data)
+		if n <= 0 || length > uint64(len(data)-n) {
+			return nil, false
+		}
+		value := data[n : n+int(length)]
+		data = data[n+int(length):]
+		return value, true
+	}
+	count, n := binary.Uvarint(data)
+	if n <= 0 || count > uint64(len(data)) {
+		return nil, corrupted
+	}
+	data = data[n:]
+	sketches := make([]KeySketch, count)
+	for i := range sketches {
+		first, ok := next()
+		if !ok {
+			return nil, corrupted
+		}
+		last, ok := next()
+		if !ok {
+			return nil, corrupted
+		}
+		entries, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, corrupted
+		}
+		data = data[n:]
+		encoded, ok := next()
+		if !ok {
+			return nil, corrupted
+		}
+		sketch, err := hyperloglog.DeserializePlus(encoded)
+		if err != nil {
+			return nil, err
+		}
+		sketches[i] = KeySketch{string(first), string(last), entries, sketch}
+	}
+	return sketches, nil
+}
+
+// Da li je key u opsegu [start, end), prazan end znaci opseg bez gornje granice
+func inRange(key, start, end string) bool {
+	return key >= start && (end == "" || key < end)
+}
+
+/*
+U sketch dodaje korisnicke kljuceve svih tabela iz opsega [start, end), prazan end znaci bez
+gornje granice. Skice delova tabele koji su ceo u opsegu se samo spajaju, a delovi koji ga
+seku, kao i tabele bez skica, se citaju od prvog kljuca u opsegu. Obrisani kljucevi se broje
+dok kompakcija ne ukloni i njihove tombstone-ove, pa je procena gornja granica.
+*/
+func DistinctKeys(start, end string, sketch *hyperloglog.HLLPlus) error {
+	for _, table := range ListTables() {
+		props := table.Properties
+		if props.NumEntries == 0 || props.LargestKey < start || (end != "" && props.SmallestKey >= end) {
+			continue
+		}
+		if len(props.KeySketches) == 0 {
+			err := scanKeys(table.Path, start, end, sketch)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		for _, chunk := range props.KeySketches {
+			if chunk.LastKey < start || (end != "" && chunk.FirstKey >= end) {
+				continue
+			}
+			if inRange(chunk.FirstKey, start, end) && inRange(chunk.LastKey, start, end) {
+				err := sketch.Merge(chunk.Sketch)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			from := start
+			if chunk.FirstKey > from {
+				from = chunk.FirstKey
+			}
+			to := end
+			if to == "" || chunk.LastKey < to {
+				// chunk.LastKey pripada delu tabele, a granica je iskljuciva
+				to = chunk.LastKey + "\x00"
+			}
+			err := scanKeys(table.Path, from, to, sketch)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Cita korisnicke kljuceve tabele iz opsega [start, end) i dodaje ih u sketch. Kao i skice
+// delova tabele, broji i kljuceve sa tombstone-om, pa rezultat ne zavisi od granica delova.
+func scanKeys(tablePath string, start, end string, sketch *hyperloglog.HLLPlus) error {
+	file, err := os.Open(tablePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header, err := readHeader(file)
+	if err != nil {
+		return err
+	}
+	props, err := readPropertiesBlock(file, header)
+	if err != nil {
+		return err
+	}
+	partitions, err := readTopLevelIndex(file, header)
+	if err != nil {
+		return err
+	}
+	from, err := findBlock(file, partitions, start)
+	if err != nil {
+		return err
+	}
+	return scanData(file, from, header.dataEnd, props.Compression, func(entry memTable.MemTableEntry) bool {
+		key := entry.GetKey()
+		if end != "" && key >= end {
+			return false
+		}
+		if key >= start && !memTable.IsInternalKey(key) {
+			sketch.Add(key)
+		}
+		return true
+	})
+}
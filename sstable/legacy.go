@@ -0,0 +1,131 @@
+package sstable
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+/*
+Tabele napravljene pre particionisanog indeksa imaju zaglavlje: kraj data zone, kraj indeksa,
+pocetak i duzina bloom filtera, a iza data zone indeks, summary i filter. Novi citaci takve
+tabele odbijaju, ali su zapisi data zone isti u oba formata, pa se tabela prevodi tako sto se
+data zona procita redom i prepise Writer-om. Prevedena tabela zadrzava ime, a time i nivo i
+redosled u odnosu na ostale tabele.
+*/
+
+// Da li je tabela u starom formatu: zaglavlje ili properties blok se ne mogu procitati
+func IsLegacyTable(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header, err := readHeader(file)
+	if err != nil {
+		return true
+	}
+	_, err = readPropertiesBlock(file, header)
+	return err != nil
+}
+
+// Prevodi jednu tabelu starog formata u novi, na mestu
+func ConvertLegacyTable(path string) error {
+	unixTime, level, ok := ParseTableName(filepath.Base(path))
+	if !ok {
+		return errors.New("sstable: not a table name: " + path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, 8)
+	_, err = file.ReadAt(buffer, 0)
+	if err != nil {
+		return err
+	}
+	dataEnd := binary.LittleEndian.Uint64(buffer)
+	if dataEnd < HEADER_SIZE {
+		return errors.New("sstable: invalid legacy header: " + path)
+	}
+
+	tmpDir := path + ".convert.tmp"
+	err = os.MkdirAll(tmpDir, 0755)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writer := NewWriterInDir(tmpDir, level, 0)
+	reader := bufio.NewReader(io.NewSectionReader(file, HEADER_SIZE, int64(dataEnd-HEADER_SIZE)))
+	for {
+		entry, _, err := readRecord(reader, "")
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writer.Abort()
+			return err
+		}
+		err = writer.Add(entry.GetKey(), entry.GetValue(), RecordMeta{entry.GetTimeStamp(), entry.GetTombstone()})
+		if err != nil {
+			writer.Abort()
+			return err
+		}
+	}
+	paths, err := writer.Finish()
+	if err != nil {
+		return err
+	}
+	file.Close()
+	if len(paths) == 0 {
+		// prazna tabela nema zapisa koje treba sacuvati
+		ForgetTable(path)
+		return os.Remove(path)
+	}
+
+	converted, _, _ := ParseTableName(filepath.Base(paths[0]))
+	err = os.Rename(MerklePath(tmpDir, converted), MerklePath(filepath.Dir(path), unixTime))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	ForgetTable(path)
+	return os.Rename(paths[0], path)
+}
+
+// Prevodi sve tabele starog formata iz direktorijuma dir i vraca njihove putanje
+func ConvertLegacyTables(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if _, _, ok := ParseTableName(entry.Name()); ok && !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var converted []string
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if !IsLegacyTable(path) {
+			continue
+		}
+		err = ConvertLegacyTable(path)
+		if err != nil {
+			return converted, err
+		}
+		converted = append(converted, path)
+	}
+	return converted, nil
+}
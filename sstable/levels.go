@@ -0,0 +1,231 @@
+package sstable
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"projekat_nasp/memTable"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Nivo na koji se upisuju tabele nastale iz memtabela. Samo se na ovom nivou tabele
+// smeju medjusobno preklapati, na nivoima ispod njega su opsezi kljuceva disjunktni.
+const FLUSH_LEVEL = 0
+
+// Opis jedne tabele na disku: putanja, nivo i redni broj (unix vreme iz imena fajla) i njeni properties
+type TableInfo struct {
+	Path       string
+	Name       string
+	Level      int
+	Sequence   int64
+	Properties *TableProperties
+}
+
+// Tabele su nepromenljive pa se njihovi properties citaju samo jednom
+var (
+	propertiesCache      = make(map[string]*TableProperties)
+	propertiesCacheMutex sync.Mutex
+)
+
+func cachedProperties(tablePath string) (*TableProperties, error) {
+	propertiesCacheMutex.Lock()
+	defer propertiesCacheMutex.Unlock()
+
+	if props, ok := propertiesCache[tablePath]; ok {
+		return props, nil
+	}
+	props, err := ReadProperties(tablePath)
+	if err != nil {
+		return nil, err
+	}
+	propertiesCache[tablePath] = props
+	return props, nil
+}
+
+// Izbacuje tabelu iz kesa, poziva se kada se tabela obrise
+func ForgetTable(tablePath string) {
+	propertiesCacheMutex.Lock()
+	defer propertiesCacheMutex.Unlock()
+	delete(propertiesCache, tablePath)
+}
+
+// Premesta tabelu na drugi nivo bez ponovnog pisanja zapisa: nivo se upisuje u properties,
+// pa se menja u imenu fajla. Redni broj (i Merkle fajl) ostaju isti.
+func MoveTable(table TableInfo, level int) error {
+	props := *table.Properties
+	props.Level = uint64(level)
+	err := rewriteProperties(table.Path, &props)
+	if err != nil {
+		return err
+	}
+	newPath := filepath.Join(filepath.Dir(table.Path), "file_"+strconv.FormatInt(table.Sequence, 10)+"_"+strconv.Itoa(level)+".db")
+	err = os.Rename(table.Path, newPath)
+	ForgetTable(table.Path)
+	return err
+}
+
+// Iz imena file_<unixTime>_<level>.db vadi redni broj i nivo tabele
+func ParseTableName(name string) (sequence int64, level int, ok bool) {
+	if !strings.HasPrefix(name, "file_") || !strings.HasSuffix(name, ".db") {
+		return 0, 0, false
+	}
+	parts := strings.Split(strings.TrimSuffix(name, ".db"), "_")
+	if len(parts) != 3 {
+		return 0, 0, false
+	}
+	sequence, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	level, err = strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, false
+	}
+	return sequence, level, true
+}
+
+// Sve tabele iz data/sstable, od najnovije ka najstarijoj
+func ListTables() []TableInfo {
+	files, _ := GetTables()
+	var tables []TableInfo
+	for _, file := range files {
+		sequence, level, ok := ParseTableName(file)
+		if !ok {
+			continue
+		}
+		tablePath := path.Join("data/sstable/", file)
+		props, err := cachedProperties(tablePath)
+		if err != nil {
+			continue
+		}
+		tables = append(tables, TableInfo{tablePath, file, level, sequence, props})
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		return tables[i].Sequence > tables[j].Sequence
+	})
+	return tables
+}
+
+// Grupise tabele po nivoima, nivoi su sortirani rastuce
+func GroupByLevel(tables []TableInfo) (levels []int, byLevel map[int][]TableInfo) {
+	byLevel = make(map[int][]TableInfo)
+	for _, table := range tables {
+		if _, ok := byLevel[table.Level]; !ok {
+			levels = append(levels, table.Level)
+		}
+		byLevel[table.Level] = append(byLevel[table.Level], table)
+	}
+	sort.Ints(levels)
+	return
+}
+
+// Da li se opsezi kljuceva tabela na nivou medjusobno ne preklapaju.
+// Ako je tako vraca tabele sortirane po najmanjem kljucu.
+func NonOverlapping(tables []TableInfo) ([]TableInfo, bool) {
+	sorted := make([]TableInfo, len(tables))
+	copy(sorted, tables)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Properties.SmallestKey < sorted[j].Properties.SmallestKey
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1].Properties.LargestKey >= sorted[i].Properties.SmallestKey {
+			return nil, false
+		}
+	}
+	return sorted, true
+}
+
+// Da li tabela moze da sadrzi kljuc. Proverava se opseg iz properties, pa bloom filter
+// particije u koju kljuc pada; false znaci da kljuca u tabeli sigurno nema.
+func MayContainKey(table TableInfo, key string) bool {
+	if !table.Properties.MayContain(key) {
+		return false
+	}
+	file, err := os.Open(table.Path)
+	if err != nil {
+		return true
+	}
+	defer file.Close()
+
+	header, err := readHeader(file)
+	if err != nil {
+		return true
+	}
+	partitions, err := readTopLevelIndex(file, header)
+	if err != nil {
+		return true
+	}
+	p := findPartition(partitions, key)
+	if p < 0 {
+		return false
+	}
+	return checkFilterPartition(file, partitions[p], table.Properties.FilterPolicy, key)
+}
+
+// Trazi kljuc na jednom nivou. Ako se tabele na nivou ne preklapaju binarnom pretragom
+// se nalazi jedina tabela koja moze da sadrzi kljuc, inace se tabele obilaze od najnovije.
+func searchLevel(tables []TableInfo, key string) []memTable.MemTableEntry {
+	if sorted, ok := NonOverlapping(tables); ok {
+		i := sort.Search(len(sorted), func(i int) bool {
+			return sorted[i].Properties.SmallestKey > key
+		}) - 1
+		if i < 0 || !sorted[i].Properties.MayContain(key) {
+			return nil
+		}
+		return FindByKey([]string{key}, sorted[i].Path, true)
+	}
+
+	for _, table := range tables {
+		if !table.Properties.MayContain(key) {
+			continue
+		}
+		if found := FindByKey([]string{key}, table.Path, true); len(found) > 0 {
+			return found
+		}
+	}
+	return nil
+}
+
+/*
+Verzije kljuca od najnovije ka starijim, zakljucno sa prvom verzijom koja nije operand Merge
+operacije. Dok god je pronadjena verzija operand, pretraga nastavlja na starije tabele istog
+nivoa i na dublje nivoe, jer je za vrednost kljuca potrebna i starija istorija.
+*/
+func SearchVersions(key string) []memTable.MemTableEntry {
+	var versions []memTable.MemTableEntry
+	levels, byLevel := GroupByLevel(ListTables())
+	for _, level := range levels {
+		for _, entry := range levelVersions(byLevel[level], key) {
+			versions = append(versions, entry)
+			if entry.GetTombstone() != memTable.MERGE_OPERAND {
+				return versions
+			}
+		}
+	}
+	return versions
+}
+
+// Verzije kljuca na jednom nivou, od najnovije. Nivo bez preklapanja ima najvise jednu.
+func levelVersions(tables []TableInfo, key string) []memTable.MemTableEntry {
+	if _, ok := NonOverlapping(tables); ok {
+		return searchLevel(tables, key)
+	}
+	var versions []memTable.MemTableEntry
+	for _, table := range tables {
+		if !table.Properties.MayContain(key) {
+			continue
+		}
+		found := FindByKey([]string{key}, table.Path, true)
+		if len(found) == 0 {
+			continue
+		}
+		versions = append(versions, found[0])
+		if found[0].GetTombstone() != memTable.MERGE_OPERAND {
+			break
+		}
+	}
+	return versions
+}
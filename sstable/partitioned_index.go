@@ -0,0 +1,207 @@
+package sstable
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"projekat_nasp/bloom_filter"
+	"projekat_nasp/config"
+	"projekat_nasp/memTable"
+	"projekat_nasp/xor_filter"
+	"sort"
+)
+
+/*
+Indeks je podeljen na particije, a svaka particija ima svoj bloom filter nad kljucevima
+zapisa koje pokriva. Top-level index ima po jedan zapis za svaku particiju:
+
+	+-------------+-----------+------------------+----------------+-------------------+-----------------+
+	| KeySize(8B) | First Key | Index Offset(8B) | Index Size(8B) | Filter Offset(8B) | Filter Size(8B) |
+	+-------------+-----------+------------------+----------------+-------------------+-----------------+
+
+Pretraga cita samo top-level index, jednu particiju indeksa i njen filter.
+*/
+type indexPartition struct {
+	firstKey     string
+	indexOffset  uint64
+	indexSize    uint64
+	filterOffset uint64
+	filterSize   uint64
+}
+
+type tableHeader struct {
+	dataEnd          uint64
+	topIndexOffset   uint64
+	propertiesOffset uint64
+	propertiesSize   uint64
+}
+
+// Broj lidera blokova u jednoj particiji indeksa
+func indexPartitionSize() int {
+	size := config.GlobalConfig.IndexPartitionSize
+	if size <= 0 {
+		size = config.INDEX_PARTITION_SIZE
+	}
+	return size
+}
+
+func encodeIndexEntry(key string, offset uint64) []byte {
+	recordByte := make([]byte, K_SIZE+len([]byte(key))+VALUE_SIZE_LEN)
+	binary.LittleEndian.PutUint64(recordByte[0:K_SIZE], uint64(len([]byte(key))))
+	copy(recordByte[K_SIZE:K_SIZE+len([]byte(key))], []byte(key))
+	binary.LittleEndian.PutUint64(recordByte[K_SIZE+len([]byte(key)):], offset)
+	return recordByte
+}
+
+func encodeTopLevelIndex(partitions []indexPartition) []byte {
+	recordByte := make([]byte, 8)
+	binary.LittleEndian.PutUint64(recordByte, uint64(len(partitions)))
+	for _, p := range partitions {
+		entry := make([]byte, K_SIZE+len(p.firstKey)+4*8)
+		binary.LittleEndian.PutUint64(entry[0:K_SIZE], uint64(len(p.firstKey)))
+		copy(entry[K_SIZE:], p.firstKey)
+		pos := K_SIZE + len(p.firstKey)
+		binary.LittleEndian.PutUint64(entry[pos:], p.indexOffset)
+		binary.LittleEndian.PutUint64(entry[pos+8:], p.indexSize)
+		binary.LittleEndian.PutUint64(entry[pos+16:], p.filterOffset)
+		binary.LittleEndian.PutUint64(entry[pos+24:], p.filterSize)
+		recordByte = append(recordByte, entry...)
+	}
+	return recordByte
+}
+
+func readHeader(file *os.File) (tableHeader, error) {
+	var header tableHeader
+	buffer := make([]byte, HEADER_SIZE)
+	_, err := file.ReadAt(buffer, 0)
+	if err != nil {
+		return header, err
+	}
+	header.dataEnd = binary.LittleEndian.Uint64(buffer[0:8])
+	header.topIndexOffset = binary.LittleEndian.Uint64(buffer[8:16])
+	header.propertiesOffset = binary.LittleEndian.Uint64(buffer[16:24])
+	header.propertiesSize = binary.LittleEndian.Uint64(buffer[24:32])
+	if header.topIndexOffset < header.dataEnd || header.propertiesOffset < header.topIndexOffset {
+		return header, errors.New("sstable: invalid header")
+	}
+	return header, nil
+}
+
+func readTopLevelIndex(file *os.File, header tableHeader) ([]indexPartition, error) {
+	buffer := make([]byte, header.propertiesOffset-header.topIndexOffset)
+	_, err := file.ReadAt(buffer, int64(header.topIndexOffset))
+	if err != nil {
+		return nil, err
+	}
+	if len(buffer) < 8 {
+		return nil, errors.New("sstable: top-level index too short")
+	}
+
+	count := binary.LittleEndian.Uint64(buffer[0:8])
+	partitions := make([]indexPartition, 0, count)
+	pos := uint64(8)
+	for i := uint64(0); i < count; i++ {
+		if pos+K_SIZE > uint64(len(buffer)) {
+			return nil, errors.New("sstable: top-level index corrupted")
+		}
+		keyLen := binary.LittleEndian.Uint64(buffer[pos:])
+		pos += K_SIZE
+		if pos+keyLen+4*8 > uint64(len(buffer)) {
+			return nil, errors.New("sstable: top-level index corrupted")
+		}
+		p := indexPartition{firstKey: string(buffer[pos : pos+keyLen])}
+		pos += keyLen
+		p.indexOffset = binary.LittleEndian.Uint64(buffer[pos:])
+		p.indexSize = binary.LittleEndian.Uint64(buffer[pos+8:])
+		p.filterOffset = binary.LittleEndian.Uint64(buffer[pos+16:])
+		p.filterSize = binary.LittleEndian.Uint64(buffer[pos+24:])
+		pos += 4 * 8
+		partitions = append(partitions, p)
+	}
+	return partitions, nil
+}
+
+// Vraca poslednju particiju ciji je prvi kljuc <= key, odnosno -1 ako je key manji od svih
+func findPartition(partitions []indexPartition, key string) int {
+	return sort.Search(len(partitions), func(i int) bool {
+		return partitions[i].firstKey > key
+	}) - 1
+}
+
+// Cita lidere blokova i njihove pozicije u data zoni iz jedne particije indeksa
+func readIndexPartition(file *os.File, p indexPartition) ([]string, []uint64, error) {
+	buffer := make([]byte, p.indexSize)
+	_, err := file.ReadAt(buffer, int64(p.indexOffset))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var keys []string
+	var offsets []uint64
+	pos := uint64(0)
+	for pos < uint64(len(buffer)) {
+		keyLen := binary.LittleEndian.Uint64(buffer[pos:])
+		pos += K_SIZE
+		keys = append(keys, string(buffer[pos:pos+keyLen]))
+		pos += keyLen
+		offsets = append(offsets, binary.LittleEndian.Uint64(buffer[pos:]))
+		pos += VALUE_SIZE_LEN
+	}
+	return keys, offsets, nil
+}
+
+// Proverava kljuc filterom particije, vrsta filtera je zapisana u properties tabele
+func checkFilterPartition(file *os.File, p indexPartition, policy string, key string) bool {
+	buffer := make([]byte, p.filterSize)
+	_, err := file.ReadAt(buffer, int64(p.filterOffset))
+	if err != nil {
+		return true
+	}
+	if policy == XOR_FILTER_POLICY {
+		return xor_filter.MayContain(buffer, key)
+	}
+	return bloom_filter.MayContain(buffer, key)
+}
+
+// Pozicija u data zoni od koje treba krenuti da bi se naisao na key
+func findBlock(file *os.File, partitions []indexPartition, key string) (uint64, error) {
+	p := findPartition(partitions, key)
+	if p < 0 {
+		return HEADER_SIZE, nil
+	}
+	keys, offsets, err := readIndexPartition(file, partitions[p])
+	if err != nil {
+		return 0, err
+	}
+	i := sort.Search(len(keys), func(i int) bool {
+		return keys[i] > key
+	}) - 1
+	if i < 0 {
+		i = 0
+	}
+	return offsets[i], nil
+}
+
+// Cita zapise od pozicije start do kraja data zone i predaje ih fn dok god ona vraca true.
+// Zapisi se dekodiraju prema kompresiji tabele iz properties-a.
+func scanData(file *os.File, start, dataEnd uint64, compression string, fn func(entry memTable.MemTableEntry) bool) error {
+	_, err := file.Seek(int64(start), io.SeekStart)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(file)
+	pos := start
+	for pos < dataEnd {
+		entry, n, err := readRecord(reader, compression)
+		if err != nil {
+			return err
+		}
+		pos += uint64(n)
+		if !fn(entry) {
+			return nil
+		}
+	}
+	return nil
+}
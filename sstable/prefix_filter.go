@@ -0,0 +1,125 @@
+package sstable
+
+import (
+	"fmt"
+	"projekat_nasp/bloom_filter"
+	"projekat_nasp/config"
+	"projekat_nasp/memTable"
+	"strconv"
+	"strings"
+)
+
+/*
+Prefiksni filter je bloom filter nad prefiksima korisnickih kljuceva cele tabele, interni
+kljucevi (memTable.INTERNAL_KEY_PREFIX) se u njega ne dodaju. Prefiks
+kljuca daje extractor iz konfiguracije: "fixed" uzima prvih PrefixLength bajtova, a
+"delimiter" sve do prvog PrefixDelimiter-a ukljucujuci i njega. Kljucevi bez prefiksa (kraci
+od PrefixLength, odnosno bez delimiter-a) se ne dodaju, jer nijedan upit koji filter moze da
+odbije ne moze ni da ih vrati. Extractor se cuva u properties-ima, pa promena konfiguracije
+ne utice na vec upisane tabele.
+*/
+func prefixExtractor() string {
+	switch config.GlobalConfig.PrefixExtractor {
+	case "fixed":
+		length := config.GlobalConfig.PrefixLength
+		if length <= 0 {
+			length = config.PREFIX_LENGTH
+		}
+		return fmt.Sprintf("fixed:%d", length)
+	case "delimiter":
+		delimiter := config.GlobalConfig.PrefixDelimiter
+		if delimiter == "" {
+			delimiter = config.PREFIX_DELIMITER
+		}
+		return "delimiter:" + delimiter
+	}
+	return ""
+}
+
+// Prefiks kljuca prema extractor-u u obliku "fixed:<duzina>" ili "delimiter:<delimiter>"
+func extractPrefix(extractor string, key string) (string, bool) {
+	kind, argument, found := strings.Cut(extractor, ":")
+	if !found {
+		return "", false
+	}
+	switch kind {
+	case "fixed":
+		length, err := strconv.Atoi(argument)
+		if err != nil || length <= 0 || len(key) < length {
+			return "", false
+		}
+		return key[:length], true
+	case "delimiter":
+		i := strings.Index(key, argument)
+		if argument == "" || i < 0 {
+			return "", false
+		}
+		return key[:i+len(argument)], true
+	}
+	return "", false
+}
+
+// Pamti prefiks kljuca koji se upisuje. Kljucevi stizu sortirani, pa su isti prefiksi jedan
+// do drugog i dovoljno je porediti sa poslednjim.
+func (props *TableProperties) addToPrefixFilter(key string) {
+	prefix, ok := extractPrefix(props.PrefixExtractor, key)
+	if !ok {
+		return
+	}
+	if n := len(props.prefixes); n > 0 && props.prefixes[n-1] == prefix {
+		return
+	}
+	props.prefixes = append(props.prefixes, prefix)
+}
+
+// Pravi filter od prikupljenih prefiksa, poziva se pre upisa properties bloka
+func (props *TableProperties) buildPrefixFilter() {
+	if props.PrefixExtractor == "" {
+		return
+	}
+	filter := bloom_filter.NewBloomFilterUnique(len(props.prefixes), props.BloomFalsePositiveRate)
+	for _, prefix := range props.prefixes {
+		filter.AddString(prefix)
+	}
+	props.PrefixFilter = filter.Save()
+	props.prefixes = nil
+}
+
+/*
+Da li tabela moze imati kljuc koji pocinje sa prefix. Filter se moze pitati samo kada upit
+odredjuje prefiks kljuca (nije kraci od PrefixLength, odnosno sadrzi delimiter). Za prefiks
+internog kljuca filter se ne pita, jer interni kljucevi nisu u njemu, pa se tabela odbacuje
+samo po opsegu kljuceva. U ostalim slucajevima i za tabele bez filtera odgovor je true.
+*/
+func (props *TableProperties) MayContainPrefix(prefix string) bool {
+	if props.NumEntries == 0 {
+		return false
+	}
+	if props.LargestKey < prefix || (props.SmallestKey > prefix && !strings.HasPrefix(props.SmallestKey, prefix)) {
+		return false
+	}
+	if len(props.PrefixFilter) == 0 || mayMatchInternalKeys(prefix) {
+		return true
+	}
+	extracted, ok := extractPrefix(props.PrefixExtractor, prefix)
+	if !ok {
+		return true
+	}
+	return bloom_filter.MayContain(props.PrefixFilter, extracted)
+}
+
+// Da li neki interni kljuc moze poceti sa prefix
+func mayMatchInternalKeys(prefix string) bool {
+	return memTable.IsInternalKey(prefix) || strings.HasPrefix(memTable.INTERNAL_KEY_PREFIX, prefix)
+}
+
+// Putanje tabela koje mogu imati kljuc sa prefiksom, ostale prefix scan preskace
+func PrefixScanTables(prefix string) []string {
+	var paths []string
+	for _, table := range ListTables() {
+		if table.Properties.MayContainPrefix(prefix) {
+			paths = append(paths, table.Path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,265 @@
+package sstable
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"projekat_nasp/memTable"
+	"sort"
+)
+
+/*
+Properties blok opisuje sadrzaj tabele. Upisuje se kao niz parova ime -> vrednost
+kako bi se novi podaci mogli dodavati bez menjanja formata:
+
+	+-----------+---------------+------+----------------+-------+
+	| Count(8B) | NameSize (8B) | Name | ValueSize (8B) | Value | ...
+	+-----------+---------------+------+----------------+-------+
+*/
+type TableProperties struct {
+	SmallestKey            string
+	LargestKey             string
+	NumEntries             uint64
+	NumTombstones          uint64
+	RawKeySize             uint64 // zbir duzina kljuceva
+	RawValueSize           uint64 // zbir duzina vrednosti
+	DataSize               uint64 // velicina data zone na disku
+	IndexSize              uint64
+	FilterSize             uint64
+	MinTimestamp           uint64
+	MaxTimestamp           uint64
+	Compression            string
+	FilterPolicy           string
+	BloomFalsePositiveRate float64
+	Level                  uint64
+	IndexPartitions        uint64
+	FilterPartitions       uint64
+	IngestTimestamp        uint64      // ako je tabela ucitana spolja, svi njeni zapisi imaju ovaj timestamp
+	KeySketches            []KeySketch // HyperLogLog++ korisnickih kljuceva po delovima tabele, vidi DistinctKeys
+	PrefixExtractor        string      // kako se iz kljuca dobija prefiks, prazno znaci bez prefiksnog filtera
+	PrefixFilter           []byte      // serijalizovan bloom filter prefiksa, vidi MayContainPrefix
+	prefixes               []string    // razliciti prefiksi tabele koja se pise
+}
+
+const (
+	PROP_SMALLEST_KEY      = "key.smallest"
+	PROP_LARGEST_KEY       = "key.largest"
+	PROP_NUM_ENTRIES       = "num.entries"
+	PROP_NUM_TOMBSTONES    = "num.tombstones"
+	PROP_RAW_KEY_SIZE      = "raw.key.size"
+	PROP_RAW_VALUE_SIZE    = "raw.value.size"
+	PROP_DATA_SIZE         = "data.size"
+	PROP_INDEX_SIZE        = "index.size"
+	PROP_FILTER_SIZE       = "filter.size"
+	PROP_MIN_TIMESTAMP     = "timestamp.min"
+	PROP_MAX_TIMESTAMP     = "timestamp.max"
+	PROP_COMPRESSION       = "compression"
+	PROP_FILTER_POLICY     = "filter.policy"
+	PROP_BLOOM_FPR         = "filter.bloom.fpr"
+	PROP_LEVEL             = "level"
+	PROP_INDEX_PARTITIONS  = "index.partitions"
+	PROP_FILTER_PARTITIONS = "filter.partitions"
+	PROP_INGEST_TIMESTAMP  = "ingest.timestamp"
+	PROP_KEY_SKETCHES      = "key.sketches"
+	PROP_PREFIX_EXTRACTOR  = "prefix.extractor"
+	PROP_PREFIX_FILTER     = "filter.prefix"
+)
+
+func uint64Bytes(value uint64) []byte {
+	bytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bytes, value)
+	return bytes
+}
+
+// Azurira statistike za jedan zapis koji se upisuje u tabelu
+func (props *TableProperties) addRecord(key string, valueLen int, timestamp uint64, tombstone byte) {
+	if props.NumEntries == 0 {
+		props.SmallestKey = key
+		props.MinTimestamp = timestamp
+		props.MaxTimestamp = timestamp
+	}
+	props.LargestKey = key
+	props.NumEntries++
+	if tombstone == 1 {
+		props.NumTombstones++
+	}
+	if !memTable.IsInternalKey(key) {
+		props.addToKeySketch(key)
+		props.addToPrefixFilter(key)
+	}
+	props.RawKeySize += uint64(len(key))
+	props.RawValueSize += uint64(valueLen)
+	if timestamp < props.MinTimestamp {
+		props.MinTimestamp = timestamp
+	}
+	if timestamp > props.MaxTimestamp {
+		props.MaxTimestamp = timestamp
+	}
+}
+
+// Da li opseg kljuceva tabele sadrzi key
+func (props *TableProperties) MayContain(key string) bool {
+	return props.NumEntries > 0 && key >= props.SmallestKey && key <= props.LargestKey
+}
+
+// Da li se opseg kljuceva tabele preklapa sa [first, last]
+func (props *TableProperties) Overlaps(first, last string) bool {
+	return props.NumEntries > 0 && first <= props.LargestKey && last >= props.SmallestKey
+}
+
+func (props *TableProperties) Print() {
+	fmt.Println("  keys:          ", props.SmallestKey, "-", props.LargestKey)
+	fmt.Println("  entries:       ", props.NumEntries, "( tombstones:", props.NumTombstones, ")")
+	fmt.Println("  raw size:      ", props.RawKeySize+props.RawValueSize, "B ( keys:", props.RawKeySize, "values:", props.RawValueSize, ")")
+	fmt.Println("  on-disk size:  ", props.DataSize+props.IndexSize+props.FilterSize, "B ( data:", props.DataSize, "index:", props.IndexSize, "filter:", props.FilterSize, ")")
+	fmt.Println("  timestamps:    ", props.MinTimestamp, "-", props.MaxTimestamp)
+	fmt.Println("  compression:   ", props.Compression)
+	fmt.Println("  filter:        ", props.FilterPolicy, "fpr:", props.BloomFalsePositiveRate)
+	fmt.Println("  level:         ", props.Level)
+	fmt.Println("  partitions:    ", props.IndexPartitions, "index,", props.FilterPartitions, "filter")
+	if props.IngestTimestamp != 0 {
+		fmt.Println("  ingested at:   ", props.IngestTimestamp)
+	}
+	if props.PrefixExtractor != "" {
+		fmt.Println("  prefix filter: ", props.PrefixExtractor, "(", len(props.PrefixFilter), "B )")
+	}
+	if len(props.KeySketches) > 0 {
+		fmt.Printf("  distinct keys:  ~%.0f ( %d sketches )\n", props.DistinctKeys(), len(props.KeySketches))
+	}
+}
+
+func (props *TableProperties) toMap() map[string][]byte {
+	return map[string][]byte{
+		PROP_SMALLEST_KEY:      []byte(props.SmallestKey),
+		PROP_LARGEST_KEY:       []byte(props.LargestKey),
+		PROP_NUM_ENTRIES:       uint64Bytes(props.NumEntries),
+		PROP_NUM_TOMBSTONES:    uint64Bytes(props.NumTombstones),
+		PROP_RAW_KEY_SIZE:      uint64Bytes(props.RawKeySize),
+		PROP_RAW_VALUE_SIZE:    uint64Bytes(props.RawValueSize),
+		PROP_DATA_SIZE:         uint64Bytes(props.DataSize),
+		PROP_INDEX_SIZE:        uint64Bytes(props.IndexSize),
+		PROP_FILTER_SIZE:       uint64Bytes(props.FilterSize),
+		PROP_MIN_TIMESTAMP:     uint64Bytes(props.MinTimestamp),
+		PROP_MAX_TIMESTAMP:     uint64Bytes(props.MaxTimestamp),
+		PROP_COMPRESSION:       []byte(props.Compression),
+		PROP_FILTER_POLICY:     []byte(props.FilterPolicy),
+		PROP_BLOOM_FPR:         uint64Bytes(math.Float64bits(props.BloomFalsePositiveRate)),
+		PROP_LEVEL:             uint64Bytes(props.Level),
+		PROP_INDEX_PARTITIONS:  uint64Bytes(props.IndexPartitions),
+		PROP_FILTER_PARTITIONS: uint64Bytes(props.FilterPartitions),
+		PROP_INGEST_TIMESTAMP:  uint64Bytes(props.IngestTimestamp),
+		PROP_KEY_SKETCHES:      encodeKeySketches(props.KeySketches),
+		PROP_PREFIX_EXTRACTOR:  []byte(props.PrefixExtractor),
+		PROP_PREFIX_FILTER:     props.PrefixFilter,
+	}
+}
+
+func (props *TableProperties) fromMap(values map[string][]byte) {
+	getUint := func(name string) uint64 {
+		if value, ok := values[name]; ok && len(value) == 8 {
+			return binary.LittleEndian.Uint64(value)
+		}
+		return 0
+	}
+	getString := func(name string) string {
+		return string(values[name])
+	}
+	props.SmallestKey = getString(PROP_SMALLEST_KEY)
+	props.LargestKey = getString(PROP_LARGEST_KEY)
+	props.NumEntries = getUint(PROP_NUM_ENTRIES)
+	props.NumTombstones = getUint(PROP_NUM_TOMBSTONES)
+	props.RawKeySize = getUint(PROP_RAW_KEY_SIZE)
+	props.RawValueSize = getUint(PROP_RAW_VALUE_SIZE)
+	props.DataSize = getUint(PROP_DATA_SIZE)
+	props.IndexSize = getUint(PROP_INDEX_SIZE)
+	props.FilterSize = getUint(PROP_FILTER_SIZE)
+	props.MinTimestamp = getUint(PROP_MIN_TIMESTAMP)
+	props.MaxTimestamp = getUint(PROP_MAX_TIMESTAMP)
+	props.Compression = getString(PROP_COMPRESSION)
+	props.FilterPolicy = getString(PROP_FILTER_POLICY)
+	props.BloomFalsePositiveRate = math.Float64frombits(getUint(PROP_BLOOM_FPR))
+	props.Level = getUint(PROP_LEVEL)
+	props.IndexPartitions = getUint(PROP_INDEX_PARTITIONS)
+	props.FilterPartitions = getUint(PROP_FILTER_PARTITIONS)
+	props.IngestTimestamp = getUint(PROP_INGEST_TIMESTAMP)
+	props.KeySketches, _ = decodeKeySketches(values[PROP_KEY_SKETCHES])
+	props.PrefixExtractor = getString(PROP_PREFIX_EXTRACTOR)
+	props.PrefixFilter = values[PROP_PREFIX_FILTER]
+}
+
+func (props *TableProperties) Encode() []byte {
+	values := props.toMap()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	recordByte := uint64Bytes(uint64(len(values)))
+	for _, name := range names {
+		value := values[name]
+		recordByte = append(recordByte, uint64Bytes(uint64(len(name)))...)
+		recordByte = append(recordByte, name...)
+		recordByte = append(recordByte, uint64Bytes(uint64(len(value)))...)
+		recordByte = append(recordByte, value...)
+	}
+	return recordByte
+}
+
+func DecodeProperties(data []byte) (*TableProperties, error) {
+	if len(data) < 8 {
+		return nil, errors.New("sstable: properties block too short")
+	}
+	count := binary.LittleEndian.Uint64(data[0:8])
+	values := make(map[string][]byte, count)
+	pos := uint64(8)
+	for i := uint64(0); i < count; i++ {
+		if pos+8 > uint64(len(data)) {
+			return nil, errors.New("sstable: properties block corrupted")
+		}
+		nameLen := binary.LittleEndian.Uint64(data[pos:])
+		pos += 8
+		if pos+nameLen+8 > uint64(len(data)) {
+			return nil, errors.New("sstable: properties block corrupted")
+		}
+		name := string(data[pos : pos+nameLen])
+		pos += nameLen
+		valueLen := binary.LittleEndian.Uint64(data[pos:])
+		pos += 8
+		if pos+valueLen > uint64(len(data)) {
+			return nil, errors.New("sstable: properties block corrupted")
+		}
+		values[name] = data[pos : pos+valueLen]
+		pos += valueLen
+	}
+
+	props := &TableProperties{}
+	props.fromMap(values)
+	return props, nil
+}
+
+func readPropertiesBlock(file *os.File, header tableHeader) (*TableProperties, error) {
+	buffer := make([]byte, header.propertiesSize)
+	_, err := file.ReadAt(buffer, int64(header.propertiesOffset))
+	if err != nil {
+		return nil, err
+	}
+	return DecodeProperties(buffer)
+}
+
+// Cita properties blok tabele na putanji path
+func ReadProperties(path string) (*TableProperties, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header, err := readHeader(file)
+	if err != nil {
+		return nil, err
+	}
+	return readPropertiesBlock(file, header)
+}
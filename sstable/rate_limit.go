@@ -0,0 +1,37 @@
+package sstable
+
+import (
+	"projekat_nasp/config"
+	"projekat_nasp/token_bucket"
+	"sync"
+)
+
+var (
+	ioLimiter     *token_bucket.RateLimiter
+	ioLimiterOnce sync.Once
+)
+
+/*
+Zajednicki limiter bajtova za upis tabela pri flush-u memtabela i kompakciji, da pozadinski
+upis ne bi zauzeo disk i usporio citanja. Tabele se placaju po blokovima, a kofa prima
+najvise onoliko bajtova koliko se dopuni za jednu sekundu.
+*/
+func IOLimiter() *token_bucket.RateLimiter {
+	ioLimiterOnce.Do(func() {
+		limit := int64(config.GlobalConfig.CompactionRateLimit)
+		if limit <= 0 {
+			limit = config.COMPACTION_RATE_LIMIT
+		}
+		ioLimiter = token_bucket.NewRateLimiter(limit, limit)
+	})
+	return ioLimiter
+}
+
+// Menja dozvoljen broj bajtova u sekundi u toku rada, 0 iskljucuje ogranicenje
+func SetIORateLimit(bytesPerSecond int64) {
+	limiter := IOLimiter()
+	limiter.SetLimit(bytesPerSecond)
+	if bytesPerSecond > 0 {
+		limiter.SetBurst(bytesPerSecond)
+	}
+}
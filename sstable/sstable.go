@@ -3,7 +3,6 @@ package sstable
 import (
 	"bufio"
 	"encoding/binary"
-	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
@@ -93,7 +92,7 @@ func CreateSStable(data []memTable.MemTableEntry, level int) (table *SSTable) {
 	table = &SSTable{generalFilename, generalFilename + "Data.db", generalFilename + "Index.db",
 		generalFilename + "Summary.db", generalFilename + "Filter.gob"}
 
-	filter := bloom_filter.NewBloomFilter(len(data), 2)
+	filter := bloom_filter.NewBloomFilterUnique(len(data), bloomFalsePositiveRate())
 	keys := make([]string, 0)
 	offset := make([]uint, 0) //position in the data
 	values := make([][]byte, 0)
@@ -127,7 +126,7 @@ func CreateSStable(data []memTable.MemTableEntry, level int) (table *SSTable) {
 		offset = append(offset, currentOffset)
 		values = append(values, value)
 
-		filter.Add(key)
+		filter.AddString(key)
 		crc := CRC32(value)
 		crcBytes := make([]byte, 4)
 		binary.LittleEndian.PutUint32(crcBytes, crc)
@@ -218,7 +217,7 @@ func CreateSStable_13(data []memTable.MemTableEntry, level int, st_pr int) (tabl
 	table = &SSTable{generalFilename, generalFilename + "Data.db", generalFilename + "Index.db",
 		generalFilename + "Summary.db", generalFilename + "Filter.gob"}
 
-	filter := bloom_filter.NewBloomFilter(len(data), 2)
+	filter := bloom_filter.NewBloomFilterUnique(len(data), bloomFalsePositiveRate())
 	keys := make([]string, 0)
 	offset := make([]uint, 0) //position in the data
 	values := make([][]byte, 0)
@@ -252,7 +251,7 @@ func CreateSStable_13(data []memTable.MemTableEntry, level int, st_pr int) (tabl
 		offset = append(offset, currentOffset)
 		values = append(values, value)
 
-		filter.Add(key)
+		filter.AddString(key)
 		crc := CRC32(value)
 		crcBytes := make([]byte, 4)
 		binary.LittleEndian.PutUint32(crcBytes, crc)
@@ -373,13 +372,14 @@ func (st *SSTable) SStableFind(key string, offset int64) (validator bool, value
 		}
 		crcValue := binary.LittleEndian.Uint32(crcBytes)
 
-		// Timestamp
-		timestampBytes := make([]byte, 19)
-		_, err = reader.Read(timestampBytes)
+		// Timestamp, upisuje se u 64 bajta. Kao string je poravnat nulama, da bi se
+		// timestamp-ovi tabela mogli porediti kao stringovi.
+		timestampBytes := make([]byte, 64)
+		_, err = io.ReadFull(reader, timestampBytes)
 		if err != nil {
 			panic(err)
 		}
-		timestamp = string(timestampBytes[:])
+		timestamp = fmt.Sprintf("%020d", binary.LittleEndian.Uint64(timestampBytes))
 
 		//Tombstone
 		tombstone, err := reader.ReadByte()
@@ -437,8 +437,8 @@ func (st *SSTable) SStableFind(key string, offset int64) (validator bool, value
 func (st *SSTable) SSTableQuery(key string) (ok bool, value []byte, timestamp string) { //za ključ
 	ok = false
 	value = nil
-	bf, _ := bloom_filter.LoadFromFile(st.filterFilename)
-	ok = bf.Contains(key)
+	bf, err := bloom_filter.LoadFromFile(st.filterFilename)
+	ok = err != nil || bf.Contains(key)
 	if ok {
 		ok, offset := FindSummary(key, st.summaryFilename)
 		if ok {
@@ -454,46 +454,38 @@ func (st *SSTable) SSTableQuery(key string) (ok bool, value []byte, timestamp st
 	return false, nil, ""
 }
 
-func findSSTableFilename(level string) (filename string) {
-	filenameNum := 0
-	filename = strconv.Itoa(filenameNum)
-	possibleFilename := "./data/sstable/usertable" + filename + "-lev" + level + "-TOC.txt"
-
-	for {
-		_, err := os.Stat(possibleFilename)
-		if err == nil {
-			filenameNum += 1
-			filename = strconv.Itoa(filenameNum)
-		} else if errors.Is(err, os.ErrNotExist) {
-			return
+// Imena (unix vreme) tabela odvojenih fajlova na nivou level, od najnovije ka najstarijoj
+func findSSTableFilenames(level string) (filenames []string) {
+	files, _ := GetTables()
+	suffix := "-lev" + level + "-TOC.txt"
+	for _, file := range files {
+		if strings.HasPrefix(file, "usertable") && strings.HasSuffix(file, suffix) {
+			filenames = append(filenames, strings.TrimSuffix(strings.TrimPrefix(file, "usertable"), suffix))
 		}
-		possibleFilename = "./data/sstable/usertable" + filename + "-lev" + level + "-TOC.txt"
 	}
-
+	return
 }
 
+// Nivoi se obilaze od prvog i pretraga staje na prvom nivou na kom je kljuc pronadjen.
+// Tabele ciji opseg kljuceva iz summary-ja ne sadrzi kljuc se preskacu bez citanja filtera.
 func SearchThroughSSTables(key string, maxLevels int) (found bool, oldValue []byte, table *SSTable) {
 	oldTimestamp := ""
 	found = false
-	levelNum := maxLevels
-	for ; levelNum >= 1; levelNum-- {
+	for levelNum := FLUSH_LEVEL; levelNum <= maxLevels && !found; levelNum++ {
 		level := strconv.Itoa(levelNum)
-		maxFilename := findSSTableFilename(level)
-		maxFilenameNum, _ := strconv.Atoi(maxFilename)
-		filenameNum := maxFilenameNum - 1
-		for ; filenameNum > 0; filenameNum-- {
-			filename := strconv.Itoa(filenameNum)
-			table := readSSTable(filename, level)
-			ok, value, timestamp := table.SSTableQuery(key)
-			if oldTimestamp == "" && ok {
+		for _, filename := range findSSTableFilenames(level) {
+			candidate := readSSTable(filename, level)
+			// bez procitanog opsega tabela se ne preskace
+			first, last, ok := summaryRange(candidate.summaryFilename)
+			if ok && (key < first || key > last) {
+				continue
+			}
+			ok, value, timestamp := candidate.SSTableQuery(key)
+			if ok && (!found || timestamp > oldTimestamp) {
 				oldTimestamp = timestamp
 				found = true
 				oldValue = value
-			} else if oldTimestamp != "" && ok {
-				if timestamp > oldTimestamp {
-					oldValue = value
-					found = true
-				}
+				table = candidate
 			}
 		}
 	}
@@ -526,6 +518,11 @@ func GetTables() ([]string, error) {
 	return files, nil
 }
 func CountRecords(path string) int {
+	props, err := ReadProperties(path)
+	if err == nil && props.NumEntries > 0 {
+		return int(props.NumEntries)
+	}
+
 	f, err := os.Open(path)
 	if err != nil {
 		panic(err)
@@ -2,12 +2,19 @@ package sstable
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"projekat_nasp/bloom_filter"
+	"projekat_nasp/config"
 	"projekat_nasp/memTable"
 	merkletree "projekat_nasp/merkle_tree"
+	"projekat_nasp/token_bucket"
+	"projekat_nasp/xor_filter"
+	"sync"
 	"time"
 )
 
@@ -20,242 +27,412 @@ const (
 	HEADER_SIZE         = 32
 	M_SIZE              = 8
 	K_SIZE              = 8
-	FALSE_POSITIVE_RATE = 0.001
+	BLOOM_FILTER_POLICY = "blocked_bloom"
+	XOR_FILTER_POLICY   = "xor"
+	VARINT_COMPRESSION  = "dictionary+varint"
+	BLOCK_SIZE          = 2 // broj zapisa po bloku, svaki lider bloka ima zapis u indeksu
 )
 
+/*
+Tabela koja se trenutno pise. Data zona se upisuje direktno u fajl kroz jedan bafer,
+a particije indeksa i filtera se, cim se zatvore, prebacuju u pomocne fajlove i na kraju
+nadovezuju iza data zone. U memoriji ostaje samo particija koja se puni i top-level index.
+Cvorovi Merkle stabla se takodje pisu u pomocne fajlove, po jedan za svaki nivo stabla.
+*/
 type SSTable_Unique struct {
-	dataSize     uint64
-	indexSize    uint64
-	summarySize  uint64
-	summary      uint64
-	blockLeaders []string
-	blockIndexes []uint64
-	indexLeaders []string
-	IndexIndexes []uint64
-	bF           bloom_filter.BloomFilterUnique
-	bFPosition   uint64
-	bFDataSize   uint64
-	merkleData   [][]byte
-	path         string
-	unixTime     int64
+	file             *os.File
+	writer           *bufio.Writer
+	indexSpill       *os.File
+	indexWriter      *bufio.Writer
+	filterSpill      *os.File
+	filterWriter     *bufio.Writer
+	count            int
+	dataSize         uint64
+	indexSize        uint64
+	filterSize       uint64
+	topIndexOffset   uint64
+	propertiesOffset uint64
+	propertiesSize   uint64
+	blockLeaders     []string
+	blockIndexes     []uint64
+	partitionKeys    [][]byte         // kljucevi particije koja se trenutno puni, za njen bloom filter
+	partitions       []indexPartition // top-level index
+	properties       TableProperties
+	merkle           *merkletree.Builder
+	dir              string
+	path             string
+	unixTime         int64
+	limiter          *token_bucket.RateLimiter // nil znaci bez ogranicenja
+	pendingBytes     uint64                    // upisani bajtovi koji jos nisu placeni limiteru
 }
 
-func writeBlock(recordByte *[]byte, path string) {
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
-	if err != nil {
-		panic(err)
-	}
-	defer f.Close()
-
-	w := bufio.NewWriter(f)
-	w.Write(*recordByte)
-	w.Flush()
+// Putanja do fajla sa Merkle stablom tabele napravljene u unixTime
+func MerklePath(dir string, unixTime int64) string {
+	return filepath.Join(dir, "MetaData_"+fmt.Sprint(unixTime)+".txt")
 }
 
-func writeSSTable(data *[]memTable.MemTableEntry, sstable *SSTable_Unique) {
-	block_size := 2
-	//for i := 0; i < len(*data); i++ {
-	//node := data[i]
-	for i, node := range *data {
-		in := append([]byte(node.GetKey()), node.GetValue()...)
-		sstable.merkleData = append(sstable.merkleData, in)
-		in = nil
-
-		sstable.bF.Add(([]byte(node.GetKey())))
-		if i%int(block_size) == 0 {
-			sstable.blockLeaders = append(sstable.blockLeaders, node.GetKey())
-			sstable.blockIndexes = append(sstable.blockIndexes, sstable.dataSize+HEADER_SIZE)
-		}
-		var thumbstoneByte byte
-		if node.GetTombstone() == 1 {
-			thumbstoneByte = 1
-		} else {
-			thumbstoneByte = 0
-		}
-
-		recordByte := make([]byte, len([]byte(node.GetKey()))+len(node.GetValue())+KEY_SIZE_LEN+VALUE_SIZE_LEN+TIMESTAMP_LEN+TOMBSTONE_LEN)
-		sstable.dataSize += uint64(len(recordByte))
-
-		binary.LittleEndian.PutUint64(recordByte[0:KEY_SIZE_LEN], uint64(len([]byte(node.GetKey()))))
-		binary.LittleEndian.PutUint64(recordByte[KEY_SIZE_LEN:KEY_SIZE_LEN+VALUE_SIZE_LEN], uint64(len(node.GetValue())))
-		binary.LittleEndian.PutUint64(recordByte[KEY_SIZE_LEN+VALUE_SIZE_LEN:KEY_SIZE_LEN+VALUE_SIZE_LEN+TIMESTAMP_LEN], uint64(node.GetTimeStamp()))
-		recordByte[KEY_SIZE_LEN+VALUE_SIZE_LEN+TIMESTAMP_LEN] = byte(thumbstoneByte)
-		copy(recordByte[KEY_VALUE_START:KEY_VALUE_START+len([]byte(node.GetKey()))], []byte(node.GetKey()))
-		copy(recordByte[KEY_VALUE_START+len([]byte(node.GetKey())):KEY_VALUE_START+len([]byte(node.GetKey()))+len(node.GetValue())], node.GetValue())
+// Unix vreme je deo imena tabele pa mora biti jedinstveno i kada se vise tabela napravi zaredom
+var (
+	lastUnixTime      int64
+	lastUnixTimeMutex sync.Mutex
+)
 
-		writeBlock(&recordByte, sstable.path)
+func nextUnixTime() int64 {
+	lastUnixTimeMutex.Lock()
+	defer lastUnixTimeMutex.Unlock()
+	unixTime := time.Now().UnixNano()
+	if unixTime <= lastUnixTime {
+		unixTime = lastUnixTime + 1
 	}
-	writeIndex(sstable)
-	writeHeader(sstable)
-	writeSummary(sstable)
-	writeBloomFilter(sstable)
-	merkletree.BuildMerkleTree(sstable.merkleData, sstable.unixTime)
+	lastUnixTime = unixTime
+	return unixTime
 }
 
-func writeHeader(sstable *SSTable_Unique) {
-	f, err := os.OpenFile(sstable.path, os.O_WRONLY, 0666)
+func newSSTableUnique(dir, prefix string, level int, compression string) (*SSTable_Unique, error) {
+	sstable := &SSTable_Unique{}
+	sstable.unixTime = nextUnixTime()
+	sstable.dir = dir
+	sstable.path = filepath.Join(dir, prefix+fmt.Sprint(sstable.unixTime)+"_"+fmt.Sprint(level)+".db")
+	sstable.properties.Level = uint64(level)
+	sstable.properties.Compression = compression
+	sstable.properties.BloomFalsePositiveRate = bloomFalsePositiveRate()
+	sstable.properties.PrefixExtractor = prefixExtractor()
+	sstable.properties.FilterPolicy = filterPolicy()
+	sstable.merkle = merkletree.NewBuilder(MerklePath(dir, sstable.unixTime))
+
+	var err error
+	sstable.file, err = os.Create(sstable.path)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	defer f.Close()
-
-	f.Seek(0, 0)
-
-	err = binary.Write(f, binary.LittleEndian, sstable.dataSize+HEADER_SIZE)
+	sstable.indexSpill, err = os.Create(sstable.path + ".index.tmp")
+	if err != nil {
+		sstable.abort()
+		return nil, err
+	}
+	sstable.filterSpill, err = os.Create(sstable.path + ".filter.tmp")
 	if err != nil {
-		println(err)
-		return
+		sstable.abort()
+		return nil, err
 	}
+	sstable.writer = bufio.NewWriter(sstable.file)
+	sstable.indexWriter = bufio.NewWriter(sstable.indexSpill)
+	sstable.filterWriter = bufio.NewWriter(sstable.filterSpill)
 
-	err = binary.Write(f, binary.LittleEndian, sstable.indexSize+HEADER_SIZE)
+	// mesto za zaglavlje, popunjava se u finish
+	_, err = sstable.writer.Write(make([]byte, HEADER_SIZE))
 	if err != nil {
-		println(err)
-		return
+		sstable.abort()
+		return nil, err
 	}
+	return sstable, nil
+}
 
-	err = binary.Write(f, binary.LittleEndian, sstable.bFPosition)
+func (sstable *SSTable_Unique) add(key string, value []byte, meta RecordMeta, recordByte []byte) error {
+	err := addToPartition(sstable, sstable.count, key)
+	if err != nil {
+		return err
+	}
+	sstable.properties.addRecord(key, len(value), meta.Timestamp, meta.Tombstone)
+	err = sstable.merkle.Add(append([]byte(key), value...))
 	if err != nil {
-		println(err)
-		return
+		return err
 	}
 
-	err = binary.Write(f, binary.LittleEndian, sstable.bFDataSize)
+	_, err = sstable.writer.Write(recordByte)
 	if err != nil {
-		println(err)
-		return
+		return err
 	}
+	sstable.dataSize += uint64(len(recordByte))
+	sstable.pendingBytes += uint64(len(recordByte))
+	sstable.count++
+	if sstable.count%BLOCK_SIZE == 0 {
+		return sstable.throttle()
+	}
+	return nil
+}
 
+// Placa limiteru bajtove upisane od poslednjeg poziva, poziva se jednom po bloku
+func (sstable *SSTable_Unique) throttle() error {
+	n := sstable.pendingBytes
+	sstable.pendingBytes = 0
+	if sstable.limiter == nil || n == 0 {
+		return nil
+	}
+	return sstable.limiter.WaitN(context.Background(), int(n))
 }
 
-func writeIndex(sstable *SSTable_Unique) {
+// Zapis data zone: KS(8), VS(8), TIME(8), TB(1), K(...), V(...)
+func encodeRecord(key string, value []byte, meta RecordMeta) []byte {
+	recordByte := make([]byte, len([]byte(key))+len(value)+KEY_SIZE_LEN+VALUE_SIZE_LEN+TIMESTAMP_LEN+TOMBSTONE_LEN)
+
+	binary.LittleEndian.PutUint64(recordByte[0:KEY_SIZE_LEN], uint64(len([]byte(key))))
+	binary.LittleEndian.PutUint64(recordByte[KEY_SIZE_LEN:KEY_SIZE_LEN+VALUE_SIZE_LEN], uint64(len(value)))
+	binary.LittleEndian.PutUint64(recordByte[KEY_SIZE_LEN+VALUE_SIZE_LEN:KEY_SIZE_LEN+VALUE_SIZE_LEN+TIMESTAMP_LEN], meta.Timestamp)
+	recordByte[KEY_SIZE_LEN+VALUE_SIZE_LEN+TIMESTAMP_LEN] = meta.Tombstone
+	copy(recordByte[KEY_VALUE_START:KEY_VALUE_START+len([]byte(key))], []byte(key))
+	copy(recordByte[KEY_VALUE_START+len([]byte(key)):], value)
+	return recordByte
+}
 
-	block_size := 2
-	for i, key := range sstable.blockLeaders {
-		if i%int(block_size) == 0 {
-			sstable.indexLeaders = append(sstable.indexLeaders, key)
-			sstable.IndexIndexes = append(sstable.IndexIndexes, sstable.dataSize+HEADER_SIZE+sstable.indexSize)
+// Dodaje kljuc u particiju koja se trenutno puni. Svaki BLOCK_SIZE-ti zapis je lider bloka
+// i ulazi u indeks, a kada particija dobije partitionSize lidera zatvara se i pravi joj se bloom filter.
+func addToPartition(sstable *SSTable_Unique, i int, key string) error {
+	if i%BLOCK_SIZE == 0 {
+		if len(sstable.blockLeaders) == indexPartitionSize() {
+			err := closePartition(sstable)
+			if err != nil {
+				return err
+			}
 		}
-		recordByte := make([]byte, K_SIZE+len([]byte(key))+VALUE_SIZE_LEN)
-		sstable.indexSize += uint64(len(recordByte))
-		binary.LittleEndian.PutUint64(recordByte[0:K_SIZE], uint64(len([]byte(key))))
-		copy(recordByte[K_SIZE:K_SIZE+len([]byte(key))], []byte(key))
-		binary.LittleEndian.PutUint64(recordByte[K_SIZE+len([]byte(key)):], sstable.blockIndexes[i])
+		sstable.blockLeaders = append(sstable.blockLeaders, key)
+		sstable.blockIndexes = append(sstable.blockIndexes, sstable.dataSize+HEADER_SIZE)
+	}
+	sstable.partitionKeys = append(sstable.partitionKeys, []byte(key))
+	return nil
+}
 
-		writeBlock(&recordByte, sstable.path)
+// Verovatnoca lazno pozitivnog odgovora filtera tabele, iz nje se racuna broj bitova po kljucu
+func bloomFalsePositiveRate() float64 {
+	rate := config.GlobalConfig.BloomFalsePositiveRate
+	if rate <= 0 || rate >= 1 {
+		rate = config.FALSE_POSITIVE_RATE
 	}
+	return rate
+}
 
-	sstable.summary = sstable.dataSize + sstable.indexSize + HEADER_SIZE
+// Vrsta filtera particija prema config.FilterType, upisuje se u properties tabele
+func filterPolicy() string {
+	if config.GlobalConfig.FilterType == "xor" {
+		return XOR_FILTER_POLICY
+	}
+	return BLOOM_FILTER_POLICY
 }
 
-func writeSummary(sstable *SSTable_Unique) {
-	for i, key := range sstable.indexLeaders {
-		recordByte := make([]byte, K_SIZE+len([]byte(key))+VALUE_SIZE_LEN)
-		sstable.summarySize += uint64(len(recordByte))
-		binary.LittleEndian.PutUint64(recordByte[0:K_SIZE], uint64(len([]byte(key))))
-		copy(recordByte[K_SIZE:K_SIZE+len([]byte(key))], []byte(key))
-		binary.LittleEndian.PutUint64(recordByte[K_SIZE+len([]byte(key)):], sstable.IndexIndexes[i])
+// Zatvara particiju: njeni indeksni zapisi i bloom filter se upisuju u pomocne fajlove,
+// a u top-level index ide samo prvi kljuc i pozicije (relativne u odnosu na pocetak zone)
+func closePartition(sstable *SSTable_Unique) error {
+	if len(sstable.blockLeaders) == 0 {
+		return nil
+	}
+	var indexBytes []byte
+	for i, key := range sstable.blockLeaders {
+		indexBytes = append(indexBytes, encodeIndexEntry(key, sstable.blockIndexes[i])...)
+	}
 
-		writeBlock(&recordByte, sstable.path)
+	var filterBytes []byte
+	if sstable.properties.FilterPolicy == XOR_FILTER_POLICY {
+		keys := make([]string, len(sstable.partitionKeys))
+		for i, key := range sstable.partitionKeys {
+			keys[i] = string(key)
+		}
+		filterBytes = xor_filter.NewXorFilter(keys, sstable.properties.BloomFalsePositiveRate).Serialize()
+	} else {
+		bF := bloom_filter.NewBloomFilterUnique(len(sstable.partitionKeys), sstable.properties.BloomFalsePositiveRate)
+		for _, key := range sstable.partitionKeys {
+			bF.Add(key)
+		}
+		filterBytes = bF.Save()
 	}
-	sstable.bFPosition = sstable.summary + sstable.summarySize
+
+	_, err := sstable.indexWriter.Write(indexBytes)
+	if err != nil {
+		return err
+	}
+	_, err = sstable.filterWriter.Write(filterBytes)
+	if err != nil {
+		return err
+	}
+	sstable.partitions = append(sstable.partitions, indexPartition{
+		firstKey:     sstable.blockLeaders[0],
+		indexOffset:  sstable.indexSize,
+		indexSize:    uint64(len(indexBytes)),
+		filterOffset: sstable.filterSize,
+		filterSize:   uint64(len(filterBytes)),
+	})
+	sstable.indexSize += uint64(len(indexBytes))
+	sstable.filterSize += uint64(len(filterBytes))
+
+	sstable.blockLeaders = nil
+	sstable.blockIndexes = nil
+	sstable.partitionKeys = nil
+	return nil
 }
 
-func writeBloomFilter(sstable *SSTable_Unique) {
-	recordByte := make([]byte, M_SIZE+len(sstable.bF.Data))
-	binary.LittleEndian.PutUint64(recordByte[0:M_SIZE], uint64(sstable.bF.M))
-	copy(recordByte[M_SIZE:], sstable.bF.Data)
-	writeBlock(&recordByte, sstable.path)
-	sstable.bFDataSize = uint64(len(sstable.bF.Data))
-	writeHeader(sstable)
-	for _, hashFunc := range sstable.bF.HashFunctions {
-		recordByte := make([]byte, K_SIZE+len(hashFunc.Seed))
-		binary.LittleEndian.PutUint64(recordByte[0:K_SIZE], uint64(len(hashFunc.Seed)))
-		copy(recordByte[K_SIZE:], hashFunc.Seed)
-		writeBlock(&recordByte, sstable.path)
+// Prepisuje sadrzaj pomocnog fajla na kraj tabele
+func copySpill(sstable *SSTable_Unique, spill *os.File, spillWriter *bufio.Writer) error {
+	err := spillWriter.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = spill.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
 	}
+	_, err = io.Copy(sstable.writer, spill)
+	return err
 }
 
-func NewSSTable(data *[]memTable.MemTableEntry, level int) {
-	var sstable SSTable_Unique
-	sstable.unixTime = time.Now().UnixNano()
-	sstable.path = "data/sstable/file_" + fmt.Sprint(sstable.unixTime) + "_" + fmt.Sprint(level) + ".db"
-	file, err := os.Create(sstable.path)
+// Upisuje sve sto ide iza data zone: particije indeksa, particije filtera, top-level index,
+// properties i na kraju zaglavlje
+func (sstable *SSTable_Unique) finish() error {
+	err := closePartition(sstable)
 	if err != nil {
-		panic(err)
+		sstable.abort()
+		return err
 	}
-	defer file.Close()
-	sstable.dataSize = 0
-	sstable.indexSize = 0
-	sstable.summarySize = 0
-	sstable.bFDataSize = 0
-	writeHeader(&sstable)
 
-	sstable.bF = *bloom_filter.NewBloomFilterUnique(len(*data), FALSE_POSITIVE_RATE)
-	writeSSTable(data, &sstable)
-}
+	indexStart := sstable.dataSize + HEADER_SIZE
+	filterStart := indexStart + sstable.indexSize
+	for i := range sstable.partitions {
+		sstable.partitions[i].indexOffset += indexStart
+		sstable.partitions[i].filterOffset += filterStart
+	}
+	err = copySpill(sstable, sstable.indexSpill, sstable.indexWriter)
+	if err != nil {
+		sstable.abort()
+		return err
+	}
+	err = copySpill(sstable, sstable.filterSpill, sstable.filterWriter)
+	if err != nil {
+		sstable.abort()
+		return err
+	}
 
-// dz3
+	sstable.topIndexOffset = filterStart + sstable.filterSize
+	topIndex := encodeTopLevelIndex(sstable.partitions)
+	_, err = sstable.writer.Write(topIndex)
+	if err != nil {
+		sstable.abort()
+		return err
+	}
 
-// dz3
+	sstable.properties.DataSize = sstable.dataSize
+	sstable.properties.IndexSize = sstable.indexSize
+	sstable.properties.FilterSize = sstable.filterSize
+	sstable.properties.IndexPartitions = uint64(len(sstable.partitions))
+	sstable.properties.FilterPartitions = uint64(len(sstable.partitions))
+	sstable.properties.buildPrefixFilter()
+	sstable.propertiesOffset = sstable.topIndexOffset + uint64(len(topIndex))
+	properties := sstable.properties.Encode()
+	sstable.propertiesSize = uint64(len(properties))
+	_, err = sstable.writer.Write(properties)
+	if err != nil {
+		sstable.abort()
+		return err
+	}
 
-func NewSSTable_DZ3(data *[]memTable.MemTableEntry, level int) {
-	var sstable SSTable_Unique
-	sstable.unixTime = time.Now().UnixNano()
-	sstable.path = "data/sstable/test_compresion_" + fmt.Sprint(sstable.unixTime) + "_" + fmt.Sprint(level) + ".db"
-	file, err := os.Create(sstable.path)
+	// poslednji nepun blok i sve sto je upisano iza data zone
+	sstable.pendingBytes += sstable.indexSize + sstable.filterSize + uint64(len(topIndex)) + sstable.propertiesSize
+	err = sstable.throttle()
 	if err != nil {
-		panic(err)
+		sstable.abort()
+		return err
+	}
+
+	err = sstable.writer.Flush()
+	if err != nil {
+		sstable.abort()
+		return err
+	}
+	err = writeHeader(sstable)
+	if err != nil {
+		sstable.abort()
+		return err
 	}
-	defer file.Close()
-	sstable.dataSize = 0
-	sstable.indexSize = 0
-	sstable.summarySize = 0
-	sstable.bFDataSize = 0
-	writeHeader(&sstable)
 
-	sstable.bF = *bloom_filter.NewBloomFilterUnique(len(*data), FALSE_POSITIVE_RATE)
-	writeSSTable_DZ3(data, &sstable)
+	sstable.closeSpills()
+	err = sstable.file.Close()
+	if err != nil {
+		sstable.merkle.Abort()
+		return err
+	}
+	return sstable.merkle.Finish()
 }
 
-func writeSSTable_DZ3(data *[]memTable.MemTableEntry, sstable *SSTable_Unique) {
-	block_size := 2
+func (sstable *SSTable_Unique) closeSpills() {
+	if sstable.indexSpill != nil {
+		sstable.indexSpill.Close()
+		os.Remove(sstable.indexSpill.Name())
+	}
+	if sstable.filterSpill != nil {
+		sstable.filterSpill.Close()
+		os.Remove(sstable.filterSpill.Name())
+	}
+}
 
-	for i, node := range *data {
-		if node.GetTombstone() == 1 {
-			continue
-		}
+// Odustaje od tabele i brise sve sto je do sada upisano
+func (sstable *SSTable_Unique) abort() {
+	sstable.closeSpills()
+	if sstable.merkle != nil {
+		sstable.merkle.Abort()
+	}
+	if sstable.file != nil {
+		sstable.file.Close()
+		os.Remove(sstable.path)
+	}
+}
 
-		in := append([]byte(node.GetKey()), node.GetValue()...)
-		sstable.merkleData = append(sstable.merkleData, in)
-		in = nil
+// Zaglavlje: kraj data zone, pocetak top-level indeksa, pocetak i duzina properties bloka
+func writeHeader(sstable *SSTable_Unique) error {
+	header := make([]byte, HEADER_SIZE)
+	binary.LittleEndian.PutUint64(header[0:8], sstable.dataSize+HEADER_SIZE)
+	binary.LittleEndian.PutUint64(header[8:16], sstable.topIndexOffset)
+	binary.LittleEndian.PutUint64(header[16:24], sstable.propertiesOffset)
+	binary.LittleEndian.PutUint64(header[24:32], sstable.propertiesSize)
+	_, err := sstable.file.WriteAt(header, 0)
+	return err
+}
 
-		sstable.bF.Add(([]byte(node.GetKey())))
-		if i%int(block_size) == 0 {
-			sstable.blockLeaders = append(sstable.blockLeaders, node.GetKey())
-			sstable.blockIndexes = append(sstable.blockIndexes, sstable.dataSize+HEADER_SIZE)
+func NewSSTable(data *[]memTable.MemTableEntry, level int) {
+	writer := NewWriter(level, 0)
+	for _, node := range *data {
+		err := writer.Add(node.GetKey(), node.GetValue(), RecordMeta{node.GetTimeStamp(), node.GetTombstone()})
+		if err != nil {
+			panic(err)
 		}
+	}
+	_, err := writer.Finish()
+	if err != nil {
+		panic(err)
+	}
+}
 
-		// Encode the size of the value using variable-length encoding
-		sizeEncoded := encodeVarInt(uint64(len(node.GetValue())))
+// dz3
 
-		recordByte := make([]byte, KEY_SIZE_LEN+len(sizeEncoded)+TIMESTAMP_LEN+TOMBSTONE_LEN+len(node.GetValue()))
+func NewSSTable_DZ3(data *[]memTable.MemTableEntry, level int) {
+	writer := NewWriter(level, 0)
+	writer.prefix = "test_compresion_"
+	writer.compression = VARINT_COMPRESSION
+	// tombstone se mora upisati, inace bi starija vrednost iz nizih nivoa ponovo postala vidljiva
+	for _, node := range *data {
+		err := writer.Add(node.GetKey(), node.GetValue(), RecordMeta{node.GetTimeStamp(), node.GetTombstone()})
+		if err != nil {
+			panic(err)
+		}
+	}
+	_, err := writer.Finish()
+	if err != nil {
+		panic(err)
+	}
+}
 
-		binary.LittleEndian.PutUint64(recordByte[0:KEY_SIZE_LEN], uint64(len([]byte(node.GetKey()))))
-		copy(recordByte[KEY_SIZE_LEN:KEY_SIZE_LEN+len(sizeEncoded)], sizeEncoded)
-		binary.LittleEndian.PutUint64(recordByte[KEY_SIZE_LEN+len(sizeEncoded):KEY_SIZE_LEN+len(sizeEncoded)+TIMESTAMP_LEN], uint64(node.GetTimeStamp()))
-		recordByte[KEY_SIZE_LEN+len(sizeEncoded)+TIMESTAMP_LEN] = byte(node.GetTombstone())
+// Zapis sa velicinom vrednosti kodiranom promenljivom duzinom: KS(8), VS(1-10), TIME(8), TB(1), K(...), V(...)
+func encodeRecordVarInt(key string, value []byte, meta RecordMeta) []byte {
+	// Encode the size of the value using variable-length encoding
+	sizeEncoded := encodeVarInt(uint64(len(value)))
+	start := KEY_SIZE_LEN + len(sizeEncoded) + TIMESTAMP_LEN + TOMBSTONE_LEN
 
-		copy(recordByte[KEY_VALUE_START:KEY_VALUE_START+len([]byte(node.GetKey()))], []byte(node.GetKey()))
-		copy(recordByte[KEY_VALUE_START+len([]byte(node.GetKey())):KEY_VALUE_START+len([]byte(node.GetKey()))+len(node.GetValue())], node.GetValue())
+	recordByte := make([]byte, start+len([]byte(key))+len(value))
 
-		writeBlock(&recordByte, sstable.path)
-	}
+	binary.LittleEndian.PutUint64(recordByte[0:KEY_SIZE_LEN], uint64(len([]byte(key))))
+	copy(recordByte[KEY_SIZE_LEN:KEY_SIZE_LEN+len(sizeEncoded)], sizeEncoded)
+	binary.LittleEndian.PutUint64(recordByte[KEY_SIZE_LEN+len(sizeEncoded):KEY_SIZE_LEN+len(sizeEncoded)+TIMESTAMP_LEN], meta.Timestamp)
+	recordByte[KEY_SIZE_LEN+len(sizeEncoded)+TIMESTAMP_LEN] = meta.Tombstone
 
-	writeIndex(sstable)
-	writeHeader(sstable)
-	writeSummary(sstable)
-	writeBloomFilter(sstable)
-	merkletree.BuildMerkleTree(sstable.merkleData, sstable.unixTime)
+	copy(recordByte[start:start+len([]byte(key))], []byte(key))
+	copy(recordByte[start+len([]byte(key)):], value)
+	return recordByte
 }
 
 // Function to encode a uint64 value using variable-length encoding
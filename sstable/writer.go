@@ -0,0 +1,122 @@
+package sstable
+
+import (
+	"errors"
+	"projekat_nasp/token_bucket"
+)
+
+// Metapodaci zapisa koji se upisuju uz kljuc i vrednost
+type RecordMeta struct {
+	Timestamp uint64
+	Tombstone byte
+}
+
+/*
+Writer pise sortirane zapise u jednu ili vise tabela bez drzanja cele tabele u memoriji.
+Kljucevi moraju stizati strogo rastuce. Ako je targetFileSize > 0, kada data zona tabele
+predje tu velicinu tabela se zatvara i sledeci zapis ide u novu tabelu na istom nivou.
+*/
+type Writer struct {
+	dir            string
+	level          int
+	targetFileSize uint64
+	prefix         string
+	compression    string
+	current        *SSTable_Unique
+	paths          []string
+	lastKey        string
+	hasLast        bool
+	limiter        *token_bucket.RateLimiter
+}
+
+// Writer za tabele baze, upis je ogranicen zajednickim IOLimiter-om
+func NewWriter(level int, targetFileSize uint64) *Writer {
+	w := NewWriterInDir("data/sstable", level, targetFileSize)
+	w.limiter = IOLimiter()
+	return w
+}
+
+// Writer koji tabele pise u direktorijum dir, npr. za pravljenje tabela van baze koje se posle ucitavaju sa IngestFiles
+func NewWriterInDir(dir string, level int, targetFileSize uint64) *Writer {
+	return &Writer{
+		dir:            dir,
+		level:          level,
+		targetFileSize: targetFileSize,
+		prefix:         "file_",
+		compression:    "none",
+	}
+}
+
+func (w *Writer) Add(key string, value []byte, meta RecordMeta) error {
+	if w.hasLast && key <= w.lastKey {
+		return errors.New("sstable: keys must be added in strictly ascending order, got " + key + " after " + w.lastKey)
+	}
+
+	if w.current != nil && w.targetFileSize > 0 && w.current.dataSize >= w.targetFileSize {
+		err := w.finishCurrent()
+		if err != nil {
+			return err
+		}
+	}
+
+	if w.current == nil {
+		sstable, err := newSSTableUnique(w.dir, w.prefix, w.level, w.compression)
+		if err != nil {
+			return err
+		}
+		sstable.limiter = w.limiter
+		w.current = sstable
+	}
+
+	var recordByte []byte
+	if w.compression == VARINT_COMPRESSION {
+		recordByte = encodeRecordVarInt(key, value, meta)
+	} else {
+		recordByte = encodeRecord(key, value, meta)
+	}
+	err := w.current.add(key, value, meta, recordByte)
+	if err != nil {
+		return err
+	}
+	w.lastKey = key
+	w.hasLast = true
+	return nil
+}
+
+// Velicina data zone tabele koja se trenutno pise
+func (w *Writer) CurrentSize() uint64 {
+	if w.current == nil {
+		return 0
+	}
+	return w.current.dataSize
+}
+
+func (w *Writer) finishCurrent() error {
+	err := w.current.finish()
+	if err != nil {
+		w.current = nil
+		return err
+	}
+	w.paths = append(w.paths, w.current.path)
+	w.current = nil
+	return nil
+}
+
+// Zavrsava poslednju tabelu i vraca putanje svih napravljenih tabela
+func (w *Writer) Finish() ([]string, error) {
+	if w.current != nil {
+		err := w.finishCurrent()
+		if err != nil {
+			return w.paths, err
+		}
+	}
+	return w.paths, nil
+}
+
+// Odustaje od tabele koja se trenutno pise, vec zavrsene tabele ostaju
+func (w *Writer) Abort() {
+	if w.current != nil {
+		w.current.abort()
+		w.current = nil
+	}
+}
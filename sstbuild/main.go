@@ -0,0 +1,96 @@
+/*
+sstbuild pravi SSTabele van baze iz sortiranog ulaza, za kasnije ucitavanje sa Engine.IngestFiles.
+
+Ulaz je tekstualni fajl sa jednim zapisom po liniji, kljuc i vrednost su odvojeni tabom.
+Kljucevi moraju biti strogo rastuci:
+
+	go run ./sstbuild -in data.tsv -out build -size 1048576
+
+Sa -convert prevodi tabele starog formata (pre particionisanog indeksa) u zadatom direktorijumu,
+jednom, dok baza nije pokrenuta:
+
+	go run ./sstbuild -convert data/sstable
+*/
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"projekat_nasp/config"
+	"projekat_nasp/sstable"
+	"strings"
+	"time"
+)
+
+func main() {
+	in := flag.String("in", "", "ulazni fajl sa linijama kljuc<TAB>vrednost, sortiranim po kljucu")
+	out := flag.String("out", ".", "direktorijum u koji se pisu tabele")
+	size := flag.Uint64("size", 0, "ciljana velicina data zone jedne tabele u bajtovima, 0 znaci jedna tabela")
+	convert := flag.String("convert", "", "direktorijum cije se tabele starog formata prevode u novi format")
+	flag.Parse()
+
+	if *convert != "" {
+		config.GlobalConfig = *config.NewConfig("config/config.json")
+		paths, err := sstable.ConvertLegacyTables(*convert)
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	paths, err := build(*in, *out, *size)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+}
+
+func build(in, out string, size uint64) ([]string, error) {
+	config.GlobalConfig = *config.NewConfig("config/config.json")
+
+	file, err := os.Open(in)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	err = os.MkdirAll(out, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := sstable.NewWriterInDir(out, 0, size)
+	meta := sstable.RecordMeta{Timestamp: uint64(time.Now().Unix())}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		key, value, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok || key == "" {
+			writer.Abort()
+			return nil, fmt.Errorf("%s:%d: expected key<TAB>value", in, line)
+		}
+		err = writer.Add(key, []byte(value), meta)
+		if err != nil {
+			writer.Abort()
+			return nil, fmt.Errorf("%s:%d: %w", in, line, err)
+		}
+	}
+	if scanner.Err() != nil {
+		writer.Abort()
+		return nil, scanner.Err()
+	}
+	return writer.Finish()
+}
@@ -0,0 +1,103 @@
+package token_bucket
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+Token bucket sa ravnomernim dopunjavanjem: tokeni (npr. bajtovi) stizu brzinom limit u
+sekundi, a u kofi ih moze biti najvise burst. Zahtev veci od trenutnog broja tokena se ne
+odbija, vec uzima tokene unapred i ceka dok se manjak ne dopuni, pa i zahtevi veci od
+burst prolaze, samo sporije. Limit se moze menjati u toku rada.
+*/
+type RateLimiter struct {
+	mutex  sync.Mutex
+	limit  float64 // tokena u sekundi, 0 znaci bez ogranicenja
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(limit int64, burst int64) *RateLimiter {
+	return &RateLimiter{
+		limit:  float64(limit),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Dodaje tokene koji su pristigli od poslednjeg poziva
+func (rl *RateLimiter) advance(now time.Time) {
+	if rl.limit > 0 {
+		rl.tokens += now.Sub(rl.last).Seconds() * rl.limit
+		if rl.tokens > rl.burst {
+			rl.tokens = rl.burst
+		}
+	}
+	rl.last = now
+}
+
+// Uzima n tokena, a ako ih nema dovoljno ceka da se dopune ili da ctx bude otkazan
+func (rl *RateLimiter) WaitN(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rl.mutex.Lock()
+	rl.advance(time.Now())
+	if rl.limit <= 0 {
+		rl.mutex.Unlock()
+		return nil
+	}
+	rl.tokens -= float64(n)
+	if rl.tokens >= 0 {
+		rl.mutex.Unlock()
+		return nil
+	}
+	wait := time.Duration(-rl.tokens / rl.limit * float64(time.Second))
+	rl.mutex.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// zahtev nije izvrsen pa se uzeti tokeni vracaju
+		rl.mutex.Lock()
+		rl.advance(time.Now())
+		rl.tokens += float64(n)
+		if rl.tokens > rl.burst {
+			rl.tokens = rl.burst
+		}
+		rl.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Menja broj tokena u sekundi, 0 iskljucuje ogranicenje. Vec pristigli tokeni ostaju.
+func (rl *RateLimiter) SetLimit(limit int64) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	rl.advance(time.Now())
+	rl.limit = float64(limit)
+}
+
+// Menja kapacitet kofe, visak vec pristiglih tokena se odbacuje
+func (rl *RateLimiter) SetBurst(burst int64) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	rl.advance(time.Now())
+	rl.burst = float64(burst)
+	if rl.tokens > rl.burst {
+		rl.tokens = rl.burst
+	}
+}
+
+func (rl *RateLimiter) Limit() int64 {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	return int64(rl.limit)
+}
@@ -1,6 +1,8 @@
 package token_bucket
 
 import (
+	"encoding/binary"
+	"errors"
 	"time"
 )
 
@@ -9,32 +11,89 @@ type TokenBucket struct {
 	maxToken int
 	// the current number of tokens remaining in the bucket
 	currentToken int
-	//seconds it takes to fill the bucket
-	rate int64
-	//the time of the last
+	// time it takes to fill the bucket
+	rate time.Duration
+	// the time of the last refill, in unix nanoseconds
 	lastTimestamp int64
 }
 
-func NewTokenBucket(rate int64, maximumTokens int) *TokenBucket {
+// Result of a request check: whether it was allowed, how many tokens are left
+// and, when it was rejected, how long until the bucket is refilled
+type Decision struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// rate is the number of seconds it takes to fill the bucket
+func NewTokenBucket(rate float64, maximumTokens int) *TokenBucket {
 	return &TokenBucket{
 		maxToken:      maximumTokens,
 		currentToken:  maximumTokens,
-		rate:          rate,
-		lastTimestamp: time.Now().Unix(),
+		rate:          time.Duration(rate * float64(time.Second)),
+		lastTimestamp: time.Now().UnixNano(),
 	}
 }
 
 // checks if the request can be processed based on the current tokens in the bucket and the addition rate.
 func (tb *TokenBucket) CheckRequest() bool {
-	if time.Now().Unix()-tb.lastTimestamp > tb.rate {
-		tb.lastTimestamp = time.Now().Unix()
+	return tb.Take(1).Allowed
+}
+
+// takes n tokens if there are enough of them, the bucket is refilled as a whole once rate has passed.
+// A request that costs more than the capacity needs a full bucket and empties it.
+func (tb *TokenBucket) Take(n int) Decision {
+	if n > tb.maxToken {
+		n = tb.maxToken
+	}
+	now := time.Now().UnixNano()
+	if now-tb.lastTimestamp > int64(tb.rate) {
+		tb.lastTimestamp = now
 		tb.currentToken = tb.maxToken
 	}
 
-	if tb.currentToken <= 0 {
-		return false
+	if tb.currentToken < n || tb.currentToken <= 0 {
+		retryAfter := time.Duration(tb.lastTimestamp + int64(tb.rate) - now)
+		if retryAfter < 0 {
+			retryAfter = 0
+		}
+		return Decision{Allowed: false, Remaining: tb.currentToken, RetryAfter: retryAfter}
 	}
 
-	tb.currentToken--
-	return true
+	tb.currentToken -= n
+	return Decision{Allowed: true, Remaining: tb.currentToken}
+}
+
+// takes n tokens without a check, for a cost known only after the request was processed.
+// The count never goes below zero.
+func (tb *TokenBucket) Charge(n int) int {
+	tb.currentToken -= n
+	if tb.currentToken < 0 {
+		tb.currentToken = 0
+	}
+	return tb.currentToken
+}
+
+const ENCODED_SIZE = 32
+
+// bucket state as 4 little-endian uint64: maxToken, currentToken, rate, lastTimestamp
+func (tb *TokenBucket) Encode() []byte {
+	bytes := make([]byte, ENCODED_SIZE)
+	binary.LittleEndian.PutUint64(bytes[0:8], uint64(tb.maxToken))
+	binary.LittleEndian.PutUint64(bytes[8:16], uint64(tb.currentToken))
+	binary.LittleEndian.PutUint64(bytes[16:24], uint64(tb.rate))
+	binary.LittleEndian.PutUint64(bytes[24:32], uint64(tb.lastTimestamp))
+	return bytes
+}
+
+func DecodeTokenBucket(bytes []byte) (*TokenBucket, error) {
+	if len(bytes) != ENCODED_SIZE {
+		return nil, errors.New("token_bucket: invalid encoded bucket")
+	}
+	return &TokenBucket{
+		maxToken:      int(binary.LittleEndian.Uint64(bytes[0:8])),
+		currentToken:  int(binary.LittleEndian.Uint64(bytes[8:16])),
+		rate:          time.Duration(binary.LittleEndian.Uint64(bytes[16:24])),
+		lastTimestamp: int64(binary.LittleEndian.Uint64(bytes[24:32])),
+	}, nil
 }
@@ -276,15 +276,7 @@ func (wal *Wal) Recovery(table *memTable.MemTablesManager) {
 					// constantly adding how many bytes have been flushed so we can later on after full recovery simply delete no more needed entry bytes from log files
 					toDelete += sizeToDelete
 					if full != nil {
-						if config.GlobalConfig.SStableAllInOne == false {
-							if config.GlobalConfig.SStableDegree != 0 {
-								sstable.CreateSStable_13(full, 1, config.GlobalConfig.SStableDegree)
-							} else {
-								sstable.CreateSStable(full, 1)
-							}
-						} else {
-							sstable.NewSSTable(&full, 1)
-						}
+						sstable.FlushMemTable(full)
 					}
 					// move both to the next entry
 					entryBytes = entryBytes[totalSize:]
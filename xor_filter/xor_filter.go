@@ -0,0 +1,255 @@
+package xor_filter
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+const (
+	XOR_VERSION = 1
+	XOR_HEADER  = 14       // verzija (1B) | sirina otiska (1B) | seed (8B) | duzina bloka (4B)
+	XOR_SEED    = 0x726f78 // pocetno stanje generatora seed-ova, isti kljucevi daju isti filter
+)
+
+/*
+XOR filter za nepromenljive skupove kljuceva (Graf, Lemire). Svaki kljuc se preslikava u tri
+pozicije, po jednu u svakoj trecini niza otisaka, a otisci se biraju tako da je XOR otisaka na
+te tri pozicije jednak otisku kljuca. Zauzima oko 1.23 * sirina otiska bitova po kljucu, sto je
+manje od bloom filtera za istu verovatnocu greske, ali se posle pravljenja ne moze menjati.
+*/
+type XorFilter struct {
+	seed         uint64
+	blockLength  uint32
+	width        int    // sirina otiska u bajtovima, 1 ili 2
+	fingerprints []byte // 3 * blockLength otisaka, little-endian
+}
+
+// Otisak od 8 bitova daje gresku oko 1/256, a za manju trazenu verovatnocu se koristi 16 bitova
+func fingerprintWidth(falsePositiveRate float64) int {
+	if falsePositiveRate > 0 && falsePositiveRate >= 1.0/256 {
+		return 1
+	}
+	return 2
+}
+
+// FNV-1a sa finalizerom iz MurmurHash3, bez alokacija
+func hashKey(key string) uint64 {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= 1099511628211
+	}
+	return mix(hash)
+}
+
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+func splitmix64(state *uint64) uint64 {
+	*state += 0x9e3779b97f4a7c15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Preslikava x u [0, n) mnozenjem umesto deljenjem
+func reduce(x uint32, n uint32) uint32 {
+	return uint32(uint64(x) * uint64(n) >> 32)
+}
+
+func positions(hash uint64, blockLength uint32) [3]uint32 {
+	return [3]uint32{
+		reduce(uint32(hash), blockLength),
+		reduce(uint32(bits.RotateLeft64(hash, 21)), blockLength) + blockLength,
+		reduce(uint32(bits.RotateLeft64(hash, 42)), blockLength) + 2*blockLength,
+	}
+}
+
+func fingerprintOf(hash uint64, width int) uint16 {
+	fingerprint := uint16(hash ^ hash>>32)
+	if width == 1 {
+		fingerprint &= 0xff
+	}
+	return fingerprint
+}
+
+func readFingerprint(fingerprints []byte, width int, i uint32) uint16 {
+	if width == 1 {
+		return uint16(fingerprints[i])
+	}
+	return binary.LittleEndian.Uint16(fingerprints[2*i:])
+}
+
+func (f *XorFilter) set(i uint32, fingerprint uint16) {
+	if f.width == 1 {
+		f.fingerprints[i] = byte(fingerprint)
+		return
+	}
+	binary.LittleEndian.PutUint16(f.fingerprints[2*i:], fingerprint)
+}
+
+// Pravi filter od kljuceva, duplikati su dozvoljeni
+func NewXorFilter(keys []string, falsePositiveRate float64) *XorFilter {
+	hashes := make([]uint64, len(keys))
+	for i, key := range keys {
+		hashes[i] = hashKey(key)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	unique := hashes[:0]
+	for i, hash := range hashes {
+		if i == 0 || hash != hashes[i-1] {
+			unique = append(unique, hash)
+		}
+	}
+	hashes = unique
+
+	capacity := 32 + uint32(math.Ceil(1.23*float64(len(hashes))))
+	blockLength := capacity / 3
+	capacity = 3 * blockLength
+
+	type peeled struct {
+		hash     uint64
+		position uint32
+	}
+	xorMask := make([]uint64, capacity)
+	counts := make([]uint32, capacity)
+	queue := make([]uint32, 0, capacity)
+	stack := make([]peeled, 0, len(hashes))
+	state := uint64(XOR_SEED)
+	var seed uint64
+	for {
+		seed = splitmix64(&state)
+		for i := range xorMask {
+			xorMask[i] = 0
+			counts[i] = 0
+		}
+		for _, hash := range hashes {
+			mixed := mix(hash + seed)
+			for _, position := range positions(mixed, blockLength) {
+				xorMask[position] ^= mixed
+				counts[position]++
+			}
+		}
+
+		// pozicija sa tacno jednim kljucem odredjuje taj kljuc, pa se on uklanja iz
+		// ostale dve pozicije, sto moze da ostavi nove pozicije sa jednim kljucem
+		queue = queue[:0]
+		for position, count := range counts {
+			if count == 1 {
+				queue = append(queue, uint32(position))
+			}
+		}
+		stack = stack[:0]
+		for len(queue) > 0 {
+			position := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			if counts[position] != 1 {
+				continue
+			}
+			mixed := xorMask[position]
+			stack = append(stack, peeled{mixed, position})
+			for _, other := range positions(mixed, blockLength) {
+				xorMask[other] ^= mixed
+				counts[other]--
+				if counts[other] == 1 {
+					queue = append(queue, other)
+				}
+			}
+		}
+		if len(stack) == len(hashes) {
+			break
+		}
+	}
+
+	width := fingerprintWidth(falsePositiveRate)
+	filter := &XorFilter{
+		seed:         seed,
+		blockLength:  blockLength,
+		width:        width,
+		fingerprints: make([]byte, int(capacity)*width),
+	}
+	// otisci se dodeljuju obrnutim redom, pa su druge dve pozicije kljuca vec konacne
+	for i := len(stack) - 1; i >= 0; i-- {
+		fingerprint := fingerprintOf(stack[i].hash, width)
+		for _, position := range positions(stack[i].hash, blockLength) {
+			if position != stack[i].position {
+				fingerprint ^= readFingerprint(filter.fingerprints, width, position)
+			}
+		}
+		filter.set(stack[i].position, fingerprint)
+	}
+	return filter
+}
+
+func contains(fingerprints []byte, width int, seed uint64, blockLength uint32, key string) bool {
+	mixed := mix(hashKey(key) + seed)
+	fingerprint := fingerprintOf(mixed, width)
+	for _, position := range positions(mixed, blockLength) {
+		fingerprint ^= readFingerprint(fingerprints, width, position)
+	}
+	return fingerprint == 0
+}
+
+func (f *XorFilter) Contains(key string) bool {
+	return contains(f.fingerprints, f.width, f.seed, f.blockLength, key)
+}
+
+// Velicina filtera u bitovima
+func (f *XorFilter) Bits() int {
+	return len(f.fingerprints) * 8
+}
+
+// verzija | sirina otiska | seed | duzina bloka | otisci
+func (f *XorFilter) Serialize() []byte {
+	data := make([]byte, XOR_HEADER, XOR_HEADER+len(f.fingerprints))
+	data[0] = XOR_VERSION
+	data[1] = byte(f.width)
+	binary.LittleEndian.PutUint64(data[2:], f.seed)
+	binary.LittleEndian.PutUint32(data[10:], f.blockLength)
+	return append(data, f.fingerprints...)
+}
+
+var errInvalidFilter = errors.New("xor_filter: invalid encoded filter")
+
+func header(data []byte) (width int, seed uint64, blockLength uint32, err error) {
+	if len(data) < XOR_HEADER || data[0] != XOR_VERSION {
+		return 0, 0, 0, errInvalidFilter
+	}
+	width = int(data[1])
+	seed = binary.LittleEndian.Uint64(data[2:])
+	blockLength = binary.LittleEndian.Uint32(data[10:])
+	if (width != 1 && width != 2) || blockLength == 0 || uint64(len(data)-XOR_HEADER) != 3*uint64(blockLength)*uint64(width) {
+		return 0, 0, 0, errInvalidFilter
+	}
+	return width, seed, blockLength, nil
+}
+
+func Deserialize(data []byte) (*XorFilter, error) {
+	width, seed, blockLength, err := header(data)
+	if err != nil {
+		return nil, err
+	}
+	fingerprints := make([]byte, len(data)-XOR_HEADER)
+	copy(fingerprints, data[XOR_HEADER:])
+	return &XorFilter{seed, blockLength, width, fingerprints}, nil
+}
+
+// Provera kljuca direktno nad serijalizovanim filterom, bez alokacija. Za neispravne podatke
+// vraca true, kao i bloom_filter.MayContain.
+func MayContain(data []byte, key string) bool {
+	width, seed, blockLength, err := header(data)
+	if err != nil {
+		return true
+	}
+	return contains(data[XOR_HEADER:], width, seed, blockLength, key)
+}
@@ -0,0 +1,83 @@
+package xor_filter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func keys(prefix string, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprint(prefix, i)
+	}
+	return keys
+}
+
+func TestNoFalseNegatives(t *testing.T) {
+	for _, rate := range []float64{0.01, 0.0001} {
+		members := keys("key", 10000)
+		// duplikati su dozvoljeni
+		filter := NewXorFilter(append(members, members[:100]...), rate)
+		data := filter.Serialize()
+		for _, key := range members {
+			if !filter.Contains(key) || !MayContain(data, key) {
+				t.Fatalf("rate %v: %s not found", rate, key)
+			}
+		}
+	}
+}
+
+// Sirina otiska od 1 bajta daje gresku oko 1/256, a od 2 bajta oko 1/65536
+func TestFalsePositiveRate(t *testing.T) {
+	for _, c := range []struct {
+		rate float64
+		max  float64
+	}{{0.01, 0.006}, {0.0001, 0.0002}} {
+		filter := NewXorFilter(keys("key", 10000), c.rate)
+		positives := 0
+		for _, key := range keys("other", 100000) {
+			if filter.Contains(key) {
+				positives++
+			}
+		}
+		if rate := float64(positives) / 100000; rate > c.max {
+			t.Errorf("requested %v: false positive rate %.5f, want at most %v", c.rate, rate, c.max)
+		}
+	}
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	filter := NewXorFilter(keys("key", 1000), 0.01)
+	restored, err := Deserialize(filter.Serialize())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Bits() != filter.Bits() {
+		t.Fatalf("restored %d bits, want %d", restored.Bits(), filter.Bits())
+	}
+	for _, key := range append(keys("key", 1000), keys("other", 1000)...) {
+		if restored.Contains(key) != filter.Contains(key) {
+			t.Fatalf("restored filter disagrees on %s", key)
+		}
+	}
+}
+
+func TestEmptyAndInvalid(t *testing.T) {
+	filter := NewXorFilter(nil, 0.01)
+	positives := 0
+	for _, key := range keys("other", 1000) {
+		if filter.Contains(key) {
+			positives++
+		}
+	}
+	if positives > 20 {
+		t.Errorf("empty filter accepted %d of 1000 keys", positives)
+	}
+	data := NewXorFilter(keys("key", 100), 0.01).Serialize()
+	if _, err := Deserialize(data[:len(data)-1]); err == nil {
+		t.Error("truncated filter was decoded")
+	}
+	if !MayContain(data[:len(data)-1], "missing") {
+		t.Error("invalid filter rejected a key")
+	}
+}